@@ -0,0 +1,105 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/base64"
+	"html/template"
+	"io"
+	"os"
+
+	"github.com/golang/freetype/truetype"
+)
+
+//go:embed bundle.html
+var bundleHTML string
+
+var bundleTemplate = template.Must(template.New("bundle").Parse(bundleHTML))
+
+// bundleRun is the template data for a single Run in a bundle.
+type bundleRun struct {
+	Name   string
+	Frames []bundleFrame
+}
+
+// bundleFont is the template data for a font embedded in a bundle.
+type bundleFont struct {
+	Family string
+	Weight string
+	Style  string
+	URL    template.URL
+}
+
+// bundleFrame is the template data for a single Frame in a bundle.
+// Exactly one of SVG and PNG is set.
+type bundleFrame struct {
+	SVG     template.HTML
+	PNG     template.URL
+	Caption string
+	Hold    int64 // In milliseconds.
+}
+
+// writeBundle writes a self-contained HTML page containing every frame of
+// every run, with frames embedded in the given format ("svg" or "png").
+func writeBundle(w io.Writer, runs []*Run, frameFormat string) error {
+	var data struct {
+		Fonts []bundleFont
+		Runs  []bundleRun
+	}
+	// Embed the fonts so inline SVG text looks the same offline.
+	for _, name := range []string{monoFont, boldFont, italicFont, captionFont} {
+		ft, err := truetype.Parse(fontFiles[name])
+		if err != nil {
+			return err
+		}
+		info := fontInfo(ft, 0)
+		bf := bundleFont{
+			Family: info.family,
+			Weight: "normal",
+			Style:  "normal",
+			URL:    template.URL("data:font/ttf;base64," + base64.StdEncoding.EncodeToString(fontFiles[name])),
+		}
+		if info.bold {
+			bf.Weight = "bold"
+		}
+		if info.italic {
+			bf.Style = "italic"
+		}
+		data.Fonts = append(data.Fonts, bf)
+	}
+	for _, r := range runs {
+		br := bundleRun{Name: r.Name}
+		for _, f := range r.Frames {
+			var buf bytes.Buffer
+			if err := encodeFrame(&buf, frameFormat, f); err != nil {
+				return err
+			}
+			bf := bundleFrame{Caption: f.Caption, Hold: f.Hold.Milliseconds()}
+			switch frameFormat {
+			case "svg":
+				bf.SVG = template.HTML(buf.String())
+			case "png":
+				bf.PNG = template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()))
+			}
+			br.Frames = append(br.Frames, bf)
+		}
+		data.Runs = append(data.Runs, br)
+	}
+	return bundleTemplate.Execute(w, data)
+}
+
+func saveBundle(fname string, runs []*Run, frameFormat string) error {
+	f, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	if err := writeBundle(f, runs, frameFormat); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
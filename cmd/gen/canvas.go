@@ -0,0 +1,318 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"html"
+	"image/color"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/fogleman/gg"
+	"golang.org/x/image/font"
+)
+
+// canvas is the subset of *gg.Context used to draw frames. It exists so the
+// same drawing code can target both raster (PNG) and vector (SVG) output.
+type canvas interface {
+	Width() int
+	Height() int
+
+	SetColor(color.Color)
+	SetLineWidth(float64)
+	SetDash(...float64)
+	SetLineCapButt()
+	SetLineJoin(gg.LineJoin)
+	SetFontFace(font.Face)
+	MeasureString(s string) (w, h float64)
+
+	MoveTo(x, y float64)
+	LineTo(x, y float64)
+	QuadraticTo(x1, y1, x2, y2 float64)
+	ClosePath()
+	NewSubPath()
+	DrawRectangle(x, y, w, h float64)
+	DrawRoundedRectangle(x, y, w, h, r float64)
+	DrawCircle(x, y, r float64)
+	Fill()
+	Stroke()
+
+	DrawStringAnchored(s string, x, y, ax, ay float64)
+	DrawStringWrapped(s string, x, y, ax, ay, width, lineSpacing float64, align gg.Align)
+}
+
+var _ canvas = (*gg.Context)(nil)
+var _ canvas = (*svgCanvas)(nil)
+
+// svgCanvas is a canvas that records drawing operations as SVG elements.
+//
+// It mirrors gg's semantics: paths accumulate until Fill or Stroke, which
+// consume them using the current color, line width and dash pattern.
+type svgCanvas struct {
+	width, height int
+
+	color     color.Color
+	lineWidth float64
+	dashes    []float64
+	lineCap   string
+	lineJoin  string
+	face      font.Face
+	fontSize  float64
+	family    string
+	fontStyle string // Extra attributes for bold and italic text.
+
+	path       strings.Builder
+	start      [2]float64
+	hasCurrent bool
+
+	body strings.Builder
+}
+
+func newSVGCanvas(width, height int) *svgCanvas {
+	return &svgCanvas{
+		width:     width,
+		height:    height,
+		color:     color.Black,
+		lineWidth: 1,
+		lineCap:   "round",
+		lineJoin:  "miter",
+	}
+}
+
+func (c *svgCanvas) Width() int {
+	return c.width
+}
+
+func (c *svgCanvas) Height() int {
+	return c.height
+}
+
+func (c *svgCanvas) SetColor(col color.Color) {
+	c.color = col
+}
+
+func (c *svgCanvas) SetLineWidth(w float64) {
+	c.lineWidth = w
+}
+
+func (c *svgCanvas) SetDash(dashes ...float64) {
+	c.dashes = dashes
+}
+
+func (c *svgCanvas) SetLineCapButt() {
+	c.lineCap = "butt"
+}
+
+func (c *svgCanvas) SetLineJoin(j gg.LineJoin) {
+	switch j {
+	case gg.LineJoinRound:
+		c.lineJoin = "round"
+	case gg.LineJoinBevel:
+		c.lineJoin = "bevel"
+	}
+}
+
+func (c *svgCanvas) SetFontFace(f font.Face) {
+	c.face = f
+	info := faceInfo[f]
+	c.fontSize = info.size
+	c.family = info.family
+	c.fontStyle = ""
+	if info.bold {
+		c.fontStyle += ` font-weight="bold"`
+	}
+	if info.italic {
+		c.fontStyle += ` font-style="italic"`
+	}
+}
+
+func (c *svgCanvas) fontHeight() float64 {
+	return float64(c.face.Metrics().Height) / 64
+}
+
+func (c *svgCanvas) MeasureString(s string) (w, h float64) {
+	d := &font.Drawer{Face: c.face}
+	a := d.MeasureString(s)
+	return float64(a >> 6), c.fontHeight()
+}
+
+func (c *svgCanvas) MoveTo(x, y float64) {
+	fmt.Fprintf(&c.path, "M%.2f %.2f", x, y)
+	c.start = [2]float64{x, y}
+	c.hasCurrent = true
+}
+
+func (c *svgCanvas) LineTo(x, y float64) {
+	if !c.hasCurrent {
+		c.MoveTo(x, y)
+		return
+	}
+	fmt.Fprintf(&c.path, "L%.2f %.2f", x, y)
+}
+
+func (c *svgCanvas) QuadraticTo(x1, y1, x2, y2 float64) {
+	if !c.hasCurrent {
+		c.MoveTo(x1, y1)
+	}
+	fmt.Fprintf(&c.path, "Q%.2f %.2f %.2f %.2f", x1, y1, x2, y2)
+}
+
+func (c *svgCanvas) ClosePath() {
+	if c.hasCurrent {
+		c.path.WriteString("Z")
+	}
+}
+
+func (c *svgCanvas) NewSubPath() {
+	c.hasCurrent = false
+}
+
+func (c *svgCanvas) DrawRectangle(x, y, w, h float64) {
+	c.NewSubPath()
+	c.MoveTo(x, y)
+	c.LineTo(x+w, y)
+	c.LineTo(x+w, y+h)
+	c.LineTo(x, y+h)
+	c.ClosePath()
+}
+
+func (c *svgCanvas) DrawRoundedRectangle(x, y, w, h, r float64) {
+	c.NewSubPath()
+	c.MoveTo(x+r, y)
+	c.LineTo(x+w-r, y)
+	fmt.Fprintf(&c.path, "A%.2f %.2f 0 0 1 %.2f %.2f", r, r, x+w, y+r)
+	c.LineTo(x+w, y+h-r)
+	fmt.Fprintf(&c.path, "A%.2f %.2f 0 0 1 %.2f %.2f", r, r, x+w-r, y+h)
+	c.LineTo(x+r, y+h)
+	fmt.Fprintf(&c.path, "A%.2f %.2f 0 0 1 %.2f %.2f", r, r, x, y+h-r)
+	c.LineTo(x, y+r)
+	fmt.Fprintf(&c.path, "A%.2f %.2f 0 0 1 %.2f %.2f", r, r, x+r, y)
+	c.ClosePath()
+}
+
+func (c *svgCanvas) DrawCircle(x, y, r float64) {
+	c.NewSubPath()
+	c.MoveTo(x+r, y)
+	fmt.Fprintf(&c.path, "A%.2f %.2f 0 1 1 %.2f %.2f", r, r, x-r, y)
+	fmt.Fprintf(&c.path, "A%.2f %.2f 0 1 1 %.2f %.2f", r, r, x+r, y)
+	c.ClosePath()
+}
+
+func (c *svgCanvas) Fill() {
+	if c.path.Len() != 0 {
+		fmt.Fprintf(&c.body, "<path d=\"%s\" fill=\"%s\"%s/>\n", c.path.String(), svgColor(c.color), svgOpacity("fill", c.color))
+	}
+	c.clearPath()
+}
+
+func (c *svgCanvas) Stroke() {
+	if c.path.Len() != 0 {
+		fmt.Fprintf(&c.body, "<path d=\"%s\" fill=\"none\" stroke=\"%s\"%s stroke-width=\"%.2f\" stroke-linecap=\"%s\" stroke-linejoin=\"%s\"",
+			c.path.String(), svgColor(c.color), svgOpacity("stroke", c.color), c.lineWidth, c.lineCap, c.lineJoin)
+		if len(c.dashes) != 0 {
+			dashes := make([]string, len(c.dashes))
+			for i, d := range c.dashes {
+				dashes[i] = fmt.Sprintf("%.2f", d)
+			}
+			fmt.Fprintf(&c.body, " stroke-dasharray=\"%s\"", strings.Join(dashes, " "))
+		}
+		c.body.WriteString("/>\n")
+	}
+	c.clearPath()
+}
+
+func (c *svgCanvas) clearPath() {
+	c.path.Reset()
+	c.hasCurrent = false
+}
+
+func (c *svgCanvas) DrawStringAnchored(s string, x, y, ax, ay float64) {
+	w, h := c.MeasureString(s)
+	y += ay * h
+
+	// Prefer text-anchor over pre-measured offsets, so text stays anchored
+	// correctly even if the viewer substitutes a different font.
+	anchor := "start"
+	switch ax {
+	case 0:
+	case 0.5:
+		anchor = "middle"
+	case 1:
+		anchor = "end"
+	default:
+		x -= ax * w
+	}
+	fmt.Fprintf(&c.body, "<text x=\"%.2f\" y=\"%.2f\" font-family=\"%s\" font-size=\"%.2f\"%s fill=\"%s\"%s text-anchor=\"%s\" xml:space=\"preserve\">%s</text>\n",
+		x, y, c.family, c.fontSize, c.fontStyle, svgColor(c.color), svgOpacity("fill", c.color), anchor, html.EscapeString(s))
+}
+
+func (c *svgCanvas) DrawStringWrapped(s string, x, y, ax, ay, width, lineSpacing float64, align gg.Align) {
+	lines := wordWrap(c, s, width)
+
+	fh := c.fontHeight()
+	h := float64(len(lines)) * fh * lineSpacing
+	h -= (lineSpacing - 1) * fh
+
+	x -= ax * width
+	y -= ay * h
+	switch align {
+	case gg.AlignLeft:
+		ax = 0
+	case gg.AlignCenter:
+		ax = 0.5
+		x += width / 2
+	case gg.AlignRight:
+		ax = 1
+		x += width
+	}
+	for _, line := range lines {
+		c.DrawStringAnchored(line, x, y, ax, 1)
+		y += fh * lineSpacing
+	}
+}
+
+// WriteTo writes the recorded drawing as a standalone SVG document.
+func (c *svgCanvas) WriteTo(w io.Writer) (int64, error) {
+	n, err := fmt.Fprintf(w, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n%s</svg>\n",
+		c.width, c.height, c.width, c.height, c.body.String())
+	return int64(n), err
+}
+
+// wordWrap greedily breaks s into lines no wider than width, matching the
+// behavior of gg's DrawStringWrapped.
+func wordWrap(c canvas, s string, width float64) []string {
+	var result []string
+	for _, line := range strings.Split(s, "\n") {
+		x := ""
+		for _, word := range strings.Split(line, " ") {
+			next := word
+			if x != "" {
+				next = x + " " + word
+			}
+			if w, _ := c.MeasureString(next); w > width && x != "" {
+				result = append(result, x)
+				next = word
+			}
+			x = next
+		}
+		result = append(result, strings.TrimSpace(x))
+	}
+	return result
+}
+
+func svgColor(c color.Color) string {
+	r, g, b, _ := color.NRGBAModel.Convert(c).RGBA()
+	return fmt.Sprintf("#%02x%02x%02x", r>>8, g>>8, b>>8)
+}
+
+func svgOpacity(attr string, c color.Color) string {
+	_, _, _, a := color.NRGBAModel.Convert(c).RGBA()
+	if a == 0xffff {
+		return ""
+	}
+	return fmt.Sprintf(" %s-opacity=\"%.3f\"", attr, math.Round(float64(a)/0xffff*1000)/1000)
+}
@@ -0,0 +1,196 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"image"
+
+	"github.com/fogleman/gg"
+)
+
+// comparison is the second half of a Frame showing two collectors side by
+// side.
+type comparison struct {
+	titles   [2]string
+	captions [2]string
+	right    gcState
+}
+
+// startsWork reports whether cur begins a new unit of work for the
+// collector: starting a phase, visiting a root, or scanning an object, or
+// for the mutator, taking a step of its script. For
+// collectors that work a block at a time, taking the block off the work
+// list starts the unit of work for the first object scanned in it instead.
+func startsWork(prev, cur gcState) bool {
+	if prev == nil {
+		return false
+	}
+	pctx, ctx := prev.Context(), cur.Context()
+	if ctx.Root >= 0 && ctx.Root != pctx.Root || ctx.Phase != "" && ctx.Phase != pctx.Phase || cur.Heap().Steps != prev.Heap().Steps {
+		return true
+	}
+	if ctx.Block != nil && (pctx.Block == nil || blockIndex(cur.Heap(), ctx.Block) != blockIndex(prev.Heap(), pctx.Block)) {
+		return true
+	}
+	if ctx.Object != Nil && ctx.Object != pctx.Object {
+		// The block was just taken off the work list.
+		return pctx.Object != Nil || pctx.Block == nil
+	}
+	// Finishing up after the last object counts too, so both collectors
+	// reach their final state together.
+	return ctx == Empty && pctx != Empty
+}
+
+// compareRuns returns a run showing a and b side by side. The two advance
+// in lockstep by units of work: each unit is shown for as many frames as
+// the collector that takes the most steps to do it, while the other holds
+// on its last frame for that unit.
+func compareRuns(a, b *Run) *Run {
+	r := &Run{Name: "compare", Title: a.Title + " vs. " + b.Title}
+	af, bf := workUnits(a), workUnits(b)
+	for u := range max(len(af), len(bf)) {
+		ua, ub := unitAt(af, u), unitAt(bf, u)
+		for i := range max(len(ua), len(ub)) {
+			fa, fb := ua[min(i, len(ua)-1)], ub[min(i, len(ub)-1)]
+			caption := a.Title + ": " + fa.Caption + "\n" + b.Title + ": " + fb.Caption
+			r.Frames = append(r.Frames, Frame{
+				State:   fa.State,
+				Caption: caption,
+				Hold:    max(fa.Hold, fb.Hold),
+				work:    u,
+				compare: &comparison{
+					titles:   [2]string{a.Title, b.Title},
+					captions: [2]string{fa.Caption, fb.Caption},
+					right:    fb.State,
+				},
+			})
+		}
+	}
+	return r
+}
+
+// workUnits groups the frames of r by unit of work, leaving out tween
+// frames.
+func workUnits(r *Run) [][]Frame {
+	var units [][]Frame
+	for _, f := range r.Frames {
+		if f.tween != nil {
+			continue
+		}
+		for len(units) <= f.work {
+			units = append(units, nil)
+		}
+		units[f.work] = append(units[f.work], f)
+	}
+	return units
+}
+
+// unitAt returns the frames of unit u, or the last frame of the last unit
+// if the collector has already finished.
+func unitAt(units [][]Frame, u int) []Frame {
+	if u < len(units) && len(units[u]) > 0 {
+		return units[u]
+	}
+	for u = min(u, len(units)-1); u >= 0; u-- {
+		if n := len(units[u]); n > 0 {
+			return units[u][n-1:]
+		}
+	}
+	return nil
+}
+
+// drawCompare draws left and cmp.right next to each other, sharing a
+// single info and legend panel, scaled so both heaps fit. In portrait
+// frames, they're drawn one above the other instead.
+func drawCompare(c canvas, info string, left gcState, cmp *comparison, scale float64) {
+	height := c.Height() * 85 / 100 // Leave bottom 15% empty for closed captioning.
+	topPadding := int(32 * scale)
+	titleHeight := int(64 * scale)
+
+	// The shared panels are the same as in drawObjGraph, shrunk to fit
+	// the narrower column, or put side by side across the top.
+	var infoArea, legendArea image.Rectangle
+	var sideScale float64
+	var panes, captionAreas [2]image.Rectangle
+	if portrait(c) {
+		sideScale = scale
+		half := c.Width() / 2
+		infoArea = image.Rect(0, topPadding, half, topPadding+int(224*sideScale))
+		legendArea = image.Rect(half, topPadding, c.Width(), topPadding+int(256*sideScale))
+		top := max(infoArea.Max.Y, legendArea.Max.Y)
+		paneHeight := (height - top) / 2
+		captionHeight := (c.Height() - height) / 2
+		for i := range panes {
+			panes[i] = image.Rect(0, top+i*paneHeight, c.Width(), top+(i+1)*paneHeight)
+			captionAreas[i] = image.Rect(0, height+i*captionHeight, c.Width(), height+(i+1)*captionHeight)
+		}
+	} else {
+		side := c.Width() / 6
+		sideScale = scale * float64(side) / float64(c.Width()/4)
+		infoArea = image.Rect(0, topPadding, side, topPadding+int(224*sideScale))
+		legendArea = image.Rect(0, infoArea.Max.Y, side, infoArea.Max.Y+int(256*sideScale))
+		paneWidth := (c.Width() - side) / 2
+		for i := range panes {
+			panes[i] = image.Rect(side+i*paneWidth, topPadding, side+(i+1)*paneWidth, height)
+			captionAreas[i] = image.Rect(panes[i].Min.X, height, panes[i].Max.X, c.Height())
+		}
+	}
+
+	c.SetLineCapButt()
+	c.SetLineJoin(gg.LineJoinRound)
+
+	drawInfo(c, infoArea, info, sideScale)
+	drawLegend(c, legendArea, sideScale)
+
+	const blockFill = 0.85
+	states := [2]gcState{left, cmp.right}
+	var heapAreas [2]image.Rectangle
+	minScale := scale
+	for i, s := range states {
+		pane := panes[i]
+		top := pane.Min.Y + titleHeight + pacerHeight(s.Heap(), scale)
+		heapAreas[i] = image.Rect(pane.Min.X+pane.Dx()/4, top, pane.Max.X, pane.Max.Y)
+
+		// Draw both sides at the same scale, so they look alike.
+		l := layoutBlocks(heapAreas[i], s.Heap(), blockFill, scale, blockLabelWidth(c, s.Heap()))
+		minScale = min(minScale, l.scale)
+	}
+
+	for i, s := range states {
+		pane := panes[i]
+
+		// Divider.
+		c.SetColor(theme.Faded)
+		c.SetDash()
+		c.SetLineWidth(theme.Strokes.Divider * scale)
+		c.MoveTo(float64(pane.Min.X), float64(pane.Min.Y))
+		if portrait(c) {
+			c.LineTo(float64(pane.Max.X), float64(pane.Min.Y))
+		} else {
+			c.LineTo(float64(pane.Min.X), float64(pane.Max.Y))
+		}
+		c.Stroke()
+
+		c.SetColor(theme.Foreground)
+		must(setFontFace(c, boldFont, theme.Fonts.Title*scale))
+		c.DrawStringAnchored(cmp.titles[i], float64(pane.Min.X+pane.Dx()/2), float64(pane.Min.Y+titleHeight/2), 0.5, 0.5)
+
+		heapArea := heapAreas[i]
+		if ph := pacerHeight(s.Heap(), scale); ph != 0 {
+			drawPacer(c, image.Rect(pane.Min.X, heapArea.Min.Y-ph, pane.Max.X, heapArea.Min.Y), s.Heap(), scale)
+		}
+		rootsArea := image.Rect(pane.Min.X, heapArea.Min.Y, heapArea.Min.X, heapArea.Max.Y)
+		drawGraph(c, rootsArea, heapArea, nil, blockFill, minScale, s)
+
+		if captionsFlag {
+			caption := cmp.captions[i]
+			if portrait(c) {
+				// Stacked captions don't line up with their panes.
+				caption = cmp.titles[i] + ": " + caption
+			}
+			drawCaption(c, captionAreas[i], caption, scale)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"slices"
+	"strconv"
+)
+
+// Conservative scanning.
+//
+// The runtime scans most stack frames precisely, using the stack maps the
+// compiler emits to tell which words hold pointers. A frame interrupted by
+// asynchronous preemption has no stack map at the point it stopped, so the
+// runtime scans it conservatively instead: every word is taken for a
+// pointer if it holds the address of an allocated object. Integers and
+// dead pointers that happen to look like addresses then keep garbage
+// alive, which is why precise stack maps are worth the trouble.
+
+// wordValue returns the value of the word holding root r: the address it
+// points to, or the value of a scalar, if it's a number.
+func wordValue(h *Heap, r *Root) (uint64, bool) {
+	if !r.Scalar {
+		if r.Pointer == Nil {
+			return 0, true
+		}
+		return h.AddressOf(r.Pointer) + uint64(r.Interior), true
+	}
+	v, err := strconv.ParseUint(r.Value, 0, 64)
+	return v, err == nil
+}
+
+// conservativeTarget returns the object that the word holding root r
+// appears to point to, and the offset into it, or Nil if it doesn't look
+// like a pointer. Like the runtime, it takes any value that falls in an
+// allocated slot of a block for a pointer, without knowing whether it is.
+func conservativeTarget(h *Heap, r *Root) (Pointer, int) {
+	v, ok := wordValue(h, r)
+	if !ok || v == 0 {
+		return Nil, 0
+	}
+	p, off := h.FindObject(v)
+	if p == Free {
+		return Nil, 0
+	}
+	return p, off
+}
+
+// falselyRetained returns the marked objects that are only reachable
+// through scalar words that conservative scanning takes for pointers. It
+// returns nil if no roots are scanned conservatively.
+func falselyRetained(s gcState) []Pointer {
+	roots, _ := s.Roots()
+	if !slices.ContainsFunc(roots, func(r Root) bool { return r.Conservative }) {
+		return nil
+	}
+	h := s.Heap()
+
+	// Walk from the real pointers first, so the walk from the false ones
+	// only finds what nothing else keeps alive.
+	var reached, retained Set[Pointer]
+	walk := func(falsePointers bool) {
+		var stack []Pointer
+		for i := range roots {
+			r := &roots[i]
+			if r.Scalar == falsePointers && (!r.Scalar || r.Conservative) {
+				stack = append(stack, rootTargets(h, r)...)
+			}
+		}
+		for len(stack) != 0 {
+			p := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if p == Nil || reached.Has(p) {
+				continue
+			}
+			reached.Add(p)
+			if falsePointers {
+				retained.Add(p)
+			}
+			for _, f := range h.Objects[p].Fields {
+				if !f.Weak {
+					stack = append(stack, f.Pointer)
+				}
+			}
+		}
+	}
+	walk(false)
+	walk(true)
+
+	var ps []Pointer
+	for i := range h.Objects {
+		if p := Pointer(i); retained.Has(p) && s.Marked(p) {
+			ps = append(ps, p)
+		}
+	}
+	return ps
+}
@@ -0,0 +1,135 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io/fs"
+	"log"
+	"net/http"
+	"sync"
+)
+
+//go:embed editor.html
+var editorHTML []byte
+
+// editMain implements the "edit" subcommand, which hosts a local editor
+// for the scenario file named by -scenario.
+func editMain(args []string) {
+	flags := flag.NewFlagSet("edit", flag.ExitOnError)
+	addr := flags.String("addr", "localhost:8080", "address to listen on")
+	registerCommonFlags(flags)
+	flags.Parse(args)
+	if scenarioFlag == "" {
+		log.Fatal("edit requires -scenario")
+	}
+
+	ed := newEditor(scenarioFlag)
+	log.Printf("editing %s on http://%s", scenarioFlag, *addr)
+	log.Fatal(http.ListenAndServe(*addr, ed))
+}
+
+type editor struct {
+	mux  *http.ServeMux
+	path string
+
+	// mu protects the scenario file and serializes rendering.
+	mu sync.Mutex
+}
+
+func newEditor(path string) *editor {
+	ed := &editor{mux: http.NewServeMux(), path: path}
+	ed.mux.HandleFunc("GET /{$}", ed.handleIndex)
+	ed.mux.HandleFunc("GET /scenario", ed.handleLoad)
+	ed.mux.HandleFunc("PUT /scenario", ed.handleSave)
+	ed.mux.HandleFunc("POST /preview", ed.handlePreview)
+	return ed
+}
+
+func (ed *editor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ed.mux.ServeHTTP(w, r)
+}
+
+func (ed *editor) handleIndex(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(editorHTML)
+}
+
+// handleLoad serves the scenario file, or the built-in example heap if the
+// file doesn't exist yet.
+func (ed *editor) handleLoad(w http.ResponseWriter, r *http.Request) {
+	ed.mu.Lock()
+	sc, err := loadScenario(ed.path)
+	ed.mu.Unlock()
+	if errors.Is(err, fs.ErrNotExist) {
+		sc = scenarioFromHeap(makeHeap())
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(sc); err != nil {
+		log.Print(err)
+	}
+}
+
+func (ed *editor) handleSave(w http.ResponseWriter, r *http.Request) {
+	sc, ok := decodeScenario(w, r)
+	if !ok {
+		return
+	}
+	ed.mu.Lock()
+	err := sc.save(ed.path)
+	ed.mu.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("saved %s", ed.path)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlePreview renders the first frame of the posted scenario.
+func (ed *editor) handlePreview(w http.ResponseWriter, r *http.Request) {
+	sc, ok := decodeScenario(w, r)
+	if !ok {
+		return
+	}
+	roots, heap, _ := sc.Build()
+	var buf bytes.Buffer
+	ed.mu.Lock()
+	err := encodeFrame(&buf, "png", Frame{State: collectors[0].new(roots, heap)})
+	ed.mu.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(buf.Bytes())
+}
+
+// decodeScenario decodes and validates a scenario from the request body,
+// replying with an error if it's invalid.
+func decodeScenario(w http.ResponseWriter, r *http.Request) (*Scenario, bool) {
+	sc := new(Scenario)
+	if err := json.NewDecoder(r.Body).Decode(sc); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	roots, heap, err := sc.Build()
+	if err == nil && len(heap.Script) != 0 {
+		// Whether the script is valid depends on when it's collected.
+		err = runScript(collectors[0].new(roots, heap), func(gcState, bool) {})
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return sc, true
+}
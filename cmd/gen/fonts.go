@@ -0,0 +1,115 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	_ "embed"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gomonobold"
+	"golang.org/x/image/font/gofont/gomonoitalic"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+//go:embed RobotoMono-Regular.ttf
+var robotoMono []byte
+
+// Fonts that text in frames is drawn with. Labels use a monospaced font,
+// with bold for whatever is active and italic for small print. Captions
+// are easier to read in a proportional font.
+const (
+	monoFont    = "mono"
+	boldFont    = "bold"
+	italicFont  = "italic"
+	captionFont = "caption"
+)
+
+// fontFiles holds the TrueType data for each font, which -font and
+// related flags may replace.
+var fontFiles = map[string][]byte{
+	monoFont:    robotoMono,
+	boldFont:    gomonobold.TTF,
+	italicFont:  gomonoitalic.TTF,
+	captionFont: goregular.TTF,
+}
+
+// fontFlag returns a flag.Func that replaces the named font with a
+// TrueType file.
+func fontFlag(name string) func(string) error {
+	return func(path string) error {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if _, err := truetype.Parse(b); err != nil {
+			return err
+		}
+		fontFiles[name] = b
+		return nil
+	}
+}
+
+type fontFaceKey struct {
+	name string
+	size float64
+}
+
+// fontFaceInfo describes a cached font.Face for backends that reference
+// fonts by name rather than by glyph outlines.
+type fontFaceInfo struct {
+	family string
+	size   float64
+	bold   bool
+	italic bool
+}
+
+var fontCache = make(map[string]*truetype.Font)
+var faceCache = make(map[fontFaceKey]font.Face)
+var faceInfo = make(map[font.Face]fontFaceInfo)
+
+// fontSizeStep is the granularity of the font sizes setFontFace caches
+// faces for. Sizes are computed from frame and layout scales, so without
+// it nearly every frame would add faces that are never used again.
+const fontSizeStep = 0.25
+
+// setFontFace sets the font of c to the named font at the given size,
+// rounded down to a multiple of fontSizeStep so that text measured at
+// size still fits.
+func setFontFace(c canvas, name string, size float64) error {
+	size = max(math.Floor(size/fontSizeStep), 1) * fontSizeStep
+	if f, ok := faceCache[fontFaceKey{name, size}]; ok {
+		c.SetFontFace(f)
+		return nil
+	}
+	ft, ok := fontCache[name]
+	if !ok {
+		var err error
+		ft, err = truetype.Parse(fontFiles[name])
+		if err != nil {
+			return err
+		}
+		fontCache[name] = ft
+	}
+	f := truetype.NewFace(ft, &truetype.Options{Size: size})
+	faceCache[fontFaceKey{name, size}] = f
+	faceInfo[f] = fontInfo(ft, size)
+	c.SetFontFace(f)
+	return nil
+}
+
+// fontInfo returns the family and style of ft.
+func fontInfo(ft *truetype.Font, size float64) fontFaceInfo {
+	sub := strings.ToLower(ft.Name(truetype.NameIDFontSubfamily))
+	return fontFaceInfo{
+		family: ft.Name(truetype.NameIDFontFamily),
+		size:   size,
+		bold:   strings.Contains(sub, "bold"),
+		italic: strings.Contains(sub, "italic") || strings.Contains(sub, "oblique"),
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"iter"
+	"time"
+)
+
+// collector is a garbage collector whose marking can be stepped through.
+type collector interface {
+	gcState
+	Mark() iter.Seq[gcState]
+
+	// Reset clears the collector's marks, ready for another cycle.
+	Reset()
+}
+
+var collectors = []struct {
+	name  string
+	title string
+	new   func([]Root, *Heap) collector
+}{
+	{"marksweep", "Mark-sweep", func(roots []Root, heap *Heap) collector { return NewMarkSweep(roots, heap) }},
+	{"greentea", "Green Tea", func(roots []Root, heap *Heap) collector { return NewGreenTea(roots, heap) }},
+}
+
+// Run is every step of one collector's cycle over one heap.
+type Run struct {
+	Name   string
+	Title  string
+	Frames []Frame
+}
+
+// Frame is a single step of a Run.
+type Frame struct {
+	State   gcState
+	Caption string
+	Hold    time.Duration // How long to show the frame when playing.
+
+	// tween is non-nil for frames animating the transition into State.
+	tween *tween
+
+	// work is the number of units of work started by the collector as of
+	// this frame. See startsWork.
+	work int
+
+	// compare is non-nil for frames showing two collectors side by side,
+	// in which case State is the left one.
+	compare *comparison
+}
+
+// tweenHold is how long to show each tween frame.
+const tweenHold = time.Second / 30
+
+// record runs a full mark and sweep with gc, snapshotting every step. If
+// the heap has a mutator script, it runs that instead, with as many cycles
+// as it takes.
+func record(name, title string, gc collector) (*Run, error) {
+	r := &Run{Name: name, Title: title}
+	var prev gcState
+	work := 0
+	add := func(s gcState, sweep bool) {
+		snap := takeSnapshot(s)
+		caption := narrate(prev, snap)
+		if sweep || startsWork(prev, snap) {
+			work++
+		}
+		if worklistFlag && prev != nil && workListChanged(prev, snap) {
+			for i := range tweenFlag {
+				tw := &tween{from: prev, t: float64(i+1) / float64(tweenFlag+1)}
+				r.Frames = append(r.Frames, Frame{State: snap, Caption: caption, Hold: tweenHold, tween: tw, work: work})
+			}
+		}
+		r.Frames = append(r.Frames, Frame{State: snap, Caption: caption, Hold: holdFor(caption), work: work})
+		prev = snap
+	}
+	if len(gc.Heap().Script) != 0 {
+		if err := runScript(gc, add); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		return r, nil
+	}
+	for s := range gc.Mark() {
+		add(s, false)
+	}
+	Sweep(gc)
+	add(gc, true)
+	return r, nil
+}
+
+// recordAll records a run for every collector, each over a fresh heap
+// from newHeap, followed by a side-by-side run of the first two if
+// -compare is set. It fails if a mutator script uses a freed object.
+func recordAll(newHeap func() ([]Root, *Heap)) ([]*Run, error) {
+	var runs []*Run
+	for _, c := range collectors {
+		r, err := record(c.name, c.title, c.new(newHeap()))
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, r)
+	}
+	if compareFlag {
+		runs = append(runs, compareRuns(runs[0], runs[1]))
+	}
+	return runs, nil
+}
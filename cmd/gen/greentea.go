@@ -6,6 +6,12 @@ package main
 
 import "iter"
 
+// maxSpanScanSize is the largest element size of blocks that GreenTea
+// scans a block at a time. As in the runtime, objects any larger, such as
+// those in large-object spans, would gain little from being scanned
+// together, so they fall back to being queued and scanned one by one.
+const maxSpanScanSize = 512
+
 type GreenTea struct {
 	// Immutable.
 	roots []Root
@@ -14,6 +20,7 @@ type GreenTea struct {
 	// Mutable.
 	rootsVisited  int
 	queue         Queue[*Block]
+	objQueue      Queue[WorkItem] // Objects in blocks too large to scan whole.
 	marked        Set[Pointer]
 	scanned       Set[Pointer]
 	blockVisited  int
@@ -51,6 +58,11 @@ func (g *GreenTea) FieldsVisited(p Pointer) int {
 }
 
 func (g *GreenTea) Queued(p Pointer) bool {
+	for it := range g.objQueue.All() {
+		if it.Object == p {
+			return true
+		}
+	}
 	b := g.heap.BlockOf(p)
 	return b != nil && (g.queue.Has(b) || g.ctx.Block == b) && g.marked.Has(p) && !g.scanned.Has(p)
 }
@@ -63,6 +75,20 @@ func (g *GreenTea) BlockQueued(b *Block) bool {
 	return g.queue.Has(b)
 }
 
+func (g *GreenTea) WorkLists() []WorkList {
+	var objects, blocks []WorkItem
+	for it := range g.objQueue.All() {
+		objects = append(objects, it)
+	}
+	for b := range g.queue.All() {
+		blocks = append(blocks, WorkItem{Block: b})
+	}
+	return []WorkList{
+		{Name: "object queue", Items: objects},
+		{Name: "span queue", Items: blocks},
+	}
+}
+
 func (g *GreenTea) Context() Context {
 	return g.ctx
 }
@@ -77,21 +103,30 @@ func (g *GreenTea) mark(yield func(gcState) bool) {
 		return
 	}
 
-	// Roots.
+	// Roots, a set at a time.
 	for r := 0; r < len(g.roots); r++ {
 		g.rootsVisited = r
+		if phase := rootPhase(g.roots, r); phase != "" {
+			g.ctx.Root = -1
+			g.ctx.Phase = phase
+
+			// Yield new phase state.
+			if !yield(g) {
+				return
+			}
+		}
+		if !visitsRoot(&g.roots[r]) {
+			continue
+		}
 		g.ctx.Root = r
-		p := g.roots[r].Pointer
 
 		// Yield selected root state.
 		if !yield(g) {
 			return
 		}
-		if p != Nil && !g.marked.Has(p) {
-			g.marked.Add(p)
-			b := g.heap.BlockOf(p)
-			if !g.queue.Has(b) {
-				g.queue.Push(b)
+		for _, p := range rootTargets(g.heap, &g.roots[r]) {
+			if p != Nil && !g.marked.Has(p) {
+				g.shade(p)
 			}
 		}
 
@@ -104,9 +139,20 @@ func (g *GreenTea) mark(yield func(gcState) bool) {
 	// Finished with roots.
 	g.rootsVisited = len(g.roots)
 	g.ctx.Root = -1
+	g.ctx.Phase = ""
 
 	// Heap.
-	for !g.queue.Empty() {
+	for !g.queue.Empty() || !g.objQueue.Empty() {
+		// Objects too large to scan by block are taken first, and
+		// scanned one at a time, as in MarkSweep.
+		if it, ok := g.objQueue.Pop(); ok {
+			g.ctx.Block = nil
+			if !g.scan(yield, it) {
+				return
+			}
+			continue
+		}
+
 		// Take a block off the queue.
 		b, _ := g.queue.Pop()
 		g.ctx.Block = b
@@ -121,47 +167,9 @@ func (g *GreenTea) mark(yield func(gcState) bool) {
 			if !g.marked.Has(p) || g.scanned.Has(p) {
 				continue
 			}
-
-			// Iterate over the object's fields and mark new objects,
-			// adding their blocks to the queue if necessary.
-			g.ctx.Object = p
-			g.ctx.Field = -1
-
-			// Yield new active object.
-			if !yield(g) {
+			if !g.scan(yield, WorkItem{Object: p}) {
 				return
 			}
-
-			obj := &g.heap.Objects[p]
-			for i, f := range obj.Fields {
-				g.ctx.Field = i
-
-				// Yield new active field.
-				if !yield(g) {
-					return
-				}
-
-				fp := f.Pointer
-				if fp == Nil || g.marked.Has(fp) {
-					g.fieldsVisited[p]++
-					continue
-				}
-				g.marked.Add(fp)
-				b := g.heap.BlockOf(fp)
-				if !g.queue.Has(b) {
-					g.queue.Push(b)
-				}
-				g.fieldsVisited[p]++
-
-				// Yield new object marked.
-				if !yield(g) {
-					return
-				}
-			}
-			g.scanned.Add(p)
-
-			g.ctx.Object = Nil
-			g.ctx.Field = -1
 		}
 	}
 
@@ -169,9 +177,99 @@ func (g *GreenTea) mark(yield func(gcState) bool) {
 	g.ctx.Block = nil
 	g.ctx.Object = Nil
 	g.ctx.Field = -1
+	g.ctx.Oblet = -1
 
 	// Yield final state.
 	if !yield(g) {
 		return
 	}
+
+	// Deal with what wasn't marked.
+	if !finishMark(g, &g.ctx, g.resurrect, yield) {
+		return
+	}
+	g.ctx.Phase = ""
+}
+
+// scan iterates over the fields of the object in it, marking new objects
+// and queuing them, and reports whether to keep going. Objects queued on
+// their own are scanned an oblet at a time if they're large enough.
+func (g *GreenTea) scan(yield func(gcState) bool, it WorkItem) bool {
+	p := it.Object
+	g.ctx.Object = p
+	g.ctx.Field = -1
+	g.ctx.Oblet = -1
+	n := oblets(g.heap.SizeOf(p))
+	if n != 0 && g.ctx.Block == nil {
+		// Scanning the first oblet queues the rest.
+		g.ctx.Oblet = it.Offset / obletSize
+		if it.Offset == 0 {
+			for i := 1; i < n; i++ {
+				g.objQueue.Push(WorkItem{Object: p, Offset: i * obletSize})
+			}
+		}
+	}
+
+	// Yield new active object.
+	if !yield(g) {
+		return false
+	}
+
+	obj := &g.heap.Objects[p]
+	for i := 0; i < len(obj.Fields); i++ {
+		f := obj.Fields[i]
+		if !inOblet(f, g.ctx.Oblet) {
+			continue
+		}
+		g.ctx.Field = i
+		end := fieldStep(g.heap, p, i, g.ctx.Oblet)
+
+		// Yield new active field.
+		if !yield(g) {
+			return false
+		}
+
+		fp := f.Pointer
+		if fp == Nil || f.Weak || g.marked.Has(fp) {
+			g.fieldsVisited[p] += end - i
+			i = end - 1
+			continue
+		}
+		g.shade(fp)
+		g.fieldsVisited[p]++
+
+		// Yield new object marked.
+		if !yield(g) {
+			return false
+		}
+	}
+	if g.ctx.Oblet < 0 || g.ctx.Oblet == n-1 {
+		g.scanned.Add(p)
+	}
+
+	g.ctx.Object = Nil
+	g.ctx.Field = -1
+	g.ctx.Oblet = -1
+	return true
+}
+
+// shade marks p and queues it to be scanned: its whole block, unless the
+// block's objects are too large to scan that way, or p isn't in a block.
+func (g *GreenTea) shade(p Pointer) {
+	g.marked.Add(p)
+	b := g.heap.BlockOf(p)
+	if b == nil || b.ElemSize > maxSpanScanSize {
+		g.objQueue.Push(WorkItem{Object: p})
+		return
+	}
+	if !g.queue.Has(b) {
+		g.queue.Push(b)
+	}
+}
+
+// resurrect marks p, whose strong pointers are already known to point
+// only to marked objects, so it doesn't need scanning.
+func (g *GreenTea) resurrect(p Pointer) {
+	g.marked.Add(p)
+	g.scanned.Add(p)
 }
@@ -4,26 +4,119 @@
 
 package main
 
-import "slices"
+import (
+	"fmt"
+	"slices"
+)
 
+// Block is a span of memory divided into equal-sized slots for objects.
+//
+// A block with a size class is modeled on a runtime span: its element size
+// and number of pages come from the size class table, and it has as many
+// slots as fit in those pages. Objects then lists only the first few slots,
+// which are the ones drawn, and the rest are free. A block with pages but
+// no size class is a span for a single large object, which takes up all
+// its pages. Any other block is just the slots in Objects.
 type Block struct {
-	Address  uint64
-	ElemSize int
-	Objects  []Pointer
+	Address   uint64
+	ElemSize  int
+	Objects   []Pointer
+	SizeClass int // Zero if the block has no size class.
+	NPages    int // Zero if the block isn't a span.
+
+	// Allocation state, like the runtime's mspan. AllocBits has a bit
+	// per slot, set for those that held objects when the block was last
+	// swept. Slots are allocated in order from FreeIndex, skipping those
+	// whose alloc bits are set, so every slot before FreeIndex is in use.
+	// AllocCache holds the inverse of the alloc bits from FreeIndex to the
+	// end of their group of 64, so the next free slot is found by counting
+	// its trailing zeros.
+	AllocBits  []bool
+	FreeIndex  int
+	AllocCache uint64
 }
 
 func Blk(addr uint64, esize int, objs ...Pointer) Block {
-	return Block{addr, esize, objs}
+	return Block{Address: addr, ElemSize: esize, Objects: objs}
+}
+
+// Span returns a block of the given size class at addr, which must be
+// page-aligned, with objs in its first slots.
+func Span(addr uint64, class int, objs ...Pointer) Block {
+	return Block{
+		Address:   addr,
+		ElemSize:  classToSize[class],
+		Objects:   objs,
+		SizeClass: class,
+		NPages:    classToNPages[class],
+	}
+}
+
+// Name returns the name b goes by in frames and narration: the number of
+// its first 4 KiB page in hex, like "C000000" for a block at 0xc000000000,
+// or its full address if it doesn't start on a page.
+func (b *Block) Name() string {
+	if b.Address%4096 != 0 {
+		return fmt.Sprintf("%X", b.Address)
+	}
+	return fmt.Sprintf("%X", b.Address>>12)
+}
+
+// Large reports whether b is a span for a single large object.
+func (b *Block) Large() bool {
+	return b.SizeClass == 0 && b.NPages != 0
+}
+
+// NElems returns the number of slots in b.
+func (b *Block) NElems() int {
+	if b.SizeClass == 0 {
+		return len(b.Objects)
+	}
+	return b.NPages * pageSize / b.ElemSize
+}
+
+// SlotAddress returns the address of the i'th slot of b.
+func (b *Block) SlotAddress(i int) uint64 {
+	return b.Address + uint64(b.ElemSize*i)
 }
 
 type Object struct {
-	Type   string
-	Fields []Field
+	Type    string
+	Fields  []Field  // Pointer words.
+	Scalars []Scalar // Non-pointer words.
+
+	// Size is the size of the object in bytes, which may be less than
+	// the element size of its block. Zero means it fills its slot.
+	Size int
+
+	// Tiny lists the allocations packed into the object by the tiny
+	// allocator, if it holds any.
+	Tiny []TinyAlloc
+
+	// Fresh is set for objects the mutator has allocated since the last
+	// GC cycle.
+	Fresh bool
 }
 
 type Field struct {
 	Offset  int
-	Pointer Pointer
+	Pointer Pointer // The object containing the address the field holds.
+	Name    string  // From the object's type, if the heap declares it.
+
+	// Interior is the offset into Pointer's object of the address the
+	// field holds, which is zero unless it points into the middle of it.
+	Interior int
+
+	// Weak is set for weak pointers, like a weak.Pointer, which don't
+	// keep their target alive, and are cleared once it dies.
+	Weak bool
+}
+
+// Scalar is a word of an object that doesn't hold a pointer.
+type Scalar struct {
+	Offset int
+	Value  string // Shown in the word, if set.
+	Name   string // From the object's type, if the heap declares it.
 }
 
 func Obj(typ string, ptrs ...Field) Object {
@@ -31,7 +124,19 @@ func Obj(typ string, ptrs ...Field) Object {
 }
 
 func F(offset int, p Pointer) Field {
-	return Field{offset, p}
+	return Field{Offset: offset, Pointer: p}
+}
+
+func S(offset int, value string) Scalar {
+	return Scalar{Offset: offset, Value: value}
+}
+
+// SizeIn returns the size of o in bytes when it's in b.
+func (o *Object) SizeIn(b *Block) int {
+	if o.Size == 0 {
+		return b.ElemSize
+	}
+	return o.Size
 }
 
 const Nil Pointer = 0
@@ -45,6 +150,20 @@ const PointerSize = 8
 type Heap struct {
 	Objects []Object
 	Blocks  []Block
+	Types   []TypeDecl // Declarations of the objects' types, if known.
+
+	// Script is what the mutator does between GC cycles, for heaps that
+	// are collected more than once, and Steps is how much of it it's
+	// done. See runScript.
+	Script []Step
+	Steps  int
+
+	// Pacing of GC cycles: the number of cycles started so far, the
+	// bytes left in use by the last one, and the heap size in bytes that
+	// starts the next.
+	Cycle int
+	Live  int
+	Goal  int
 }
 
 func (h *Heap) BlockOf(p Pointer) *Block {
@@ -61,17 +180,89 @@ func (h *Heap) BlockIdx(p Pointer) (*Block, int) {
 	return nil, -1
 }
 
+// FindObject returns the object containing addr and the offset of addr
+// into it. Like the runtime's findObject, it finds the block whose slots
+// cover addr and divides by the block's element size to get the slot,
+// without looking at the slots in between. It returns Nil if addr isn't in
+// any block, and Free if it's in a free slot.
+func (h *Heap) FindObject(addr uint64) (Pointer, int) {
+	for i := range h.Blocks {
+		b := &h.Blocks[i]
+		if addr < b.Address || addr >= b.SlotAddress(b.NElems()) {
+			continue
+		}
+		off := int(addr - b.Address)
+		slot := off / b.ElemSize
+		if slot >= len(b.Objects) {
+			return Free, 0
+		}
+		return b.Objects[slot], off % b.ElemSize
+	}
+	return Nil, 0
+}
+
 func (h *Heap) AddressOf(p Pointer) uint64 {
 	b, i := h.BlockIdx(p)
 	if b == nil {
 		return 0
 	}
-	return b.Address + uint64(b.ElemSize*i)
+	return b.SlotAddress(i)
+}
+
+// Clone returns a deep copy of the heap.
+func (h *Heap) Clone() *Heap {
+	c := &Heap{
+		Objects: make([]Object, len(h.Objects)),
+		Blocks:  make([]Block, len(h.Blocks)),
+		Types:   h.Types,
+		Script:  h.Script,
+		Steps:   h.Steps,
+		Cycle:   h.Cycle,
+		Live:    h.Live,
+		Goal:    h.Goal,
+	}
+	for i, o := range h.Objects {
+		o.Fields = append([]Field(nil), o.Fields...)
+		o.Scalars = append([]Scalar(nil), o.Scalars...)
+		o.Tiny = append([]TinyAlloc(nil), o.Tiny...)
+		c.Objects[i] = o
+	}
+	for i, b := range h.Blocks {
+		b.Objects = append([]Pointer(nil), b.Objects...)
+		b.AllocBits = append([]bool(nil), b.AllocBits...)
+		c.Blocks[i] = b
+	}
+	return c
 }
 
 type Root struct {
-	Name    string
-	Pointer Pointer
+	Name     string
+	Pointer  Pointer
+	Interior int // Like Field.Interior.
+
+	// Where the root is, for roots other than plain variables. Roots
+	// in the same set are adjacent, and scanned together as one phase
+	// of marking.
+	Kind   RootKind
+	Set    string // Like "goroutine 1" or "data".
+	Frame  int    // Index of the stack frame holding the root.
+	Func   string // Function of that frame.
+	Offset int    // Offset of the root in its frame or segment.
+	Scalar bool   // The word holds a non-pointer, so it's not really a root.
+	Value  string // Shown in a scalar word, if set.
+
+	// Conservative is set for words of frames without stack maps,
+	// which the collector can't tell pointers from scalars in, so it
+	// scans every word of them, scalar or not, as a possible pointer.
+	Conservative bool
+
+	// For the roots of specials, the kind of special, "finalizer" or
+	// "cleanup", the object it's attached to, and whether it has been
+	// queued to run because the object died, which removes the special
+	// so that it's no longer a root.
+	Special string
+	Object  Pointer
+	Queued  bool
 }
 
 type Context struct {
@@ -79,6 +270,11 @@ type Context struct {
 	Block  *Block
 	Object Pointer
 	Field  int
+	Oblet  int // Which oblet of Object is being scanned, or -1 for all of it.
+
+	// Phase describes the phase of marking under way, like "scan
+	// goroutine 1's stack", if it's one that's shown on its own.
+	Phase string
 }
 
-var Empty = Context{-1, nil, Nil, -1}
+var Empty = Context{-1, nil, Nil, -1, -1, ""}
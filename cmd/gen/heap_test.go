@@ -0,0 +1,54 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+func TestFindObject(t *testing.T) {
+	const a, tiny, big = Pointer(2), Pointer(3), Pointer(4)
+	h := &Heap{
+		Objects: []Object{
+			Nil:  Obj("nil"),
+			Free: Obj("<free>"),
+			a:    Obj("T"),
+			tiny: {Type: tinyType},
+			big:  Obj("[1000]int"),
+		},
+		Blocks: []Block{
+			Span(0xc000000000, 2, a, Free, tiny),
+			{Address: 0xc000002000, ElemSize: pageSize, Objects: []Pointer{big}, NPages: 1},
+		},
+	}
+	for _, size := range []int{4, 6} {
+		if err := h.Objects[tiny].packTiny("x", size); err != nil {
+			t.Fatal(err)
+		}
+	}
+	second := h.Objects[tiny].Tiny[1].Offset
+
+	tests := []struct {
+		name string
+		addr uint64
+		p    Pointer
+		off  int
+	}{
+		{"start", 0xc000000000, a, 0},
+		{"interior", 0xc000000008, a, 8},
+		{"free slot", 0xc000000010, Free, 0},
+		{"tiny", 0xc000000020, tiny, 0},
+		{"second tiny", 0xc000000020 + uint64(second), tiny, second},
+		{"unlisted slot", 0xc000000030, Free, 0},
+		{"last byte of span", 0xc000001fff, Free, 0},
+		{"large object", 0xc000002000 + 4000, big, 4000},
+		{"past the heap", 0xc000004000, Nil, 0},
+		{"before the heap", 0xbfffffffff, Nil, 0},
+	}
+	for _, tt := range tests {
+		p, off := h.FindObject(tt.addr)
+		if p != tt.p || off != tt.off {
+			t.Errorf("%s: FindObject(%#x) = %d, %d, want %d, %d", tt.name, tt.addr, p, off, tt.p, tt.off)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strings"
+)
+
+// Sizes of the parts of a block when drawn at scale 1.
+const (
+	ptrWordSize = 64  // Width of one word of an object.
+	objPadding  = 16  // Space around each object in a block.
+	blockHeight = 128 // Height of a block.
+
+	// blockSpacing is how much vertical space a row of blocks needs,
+	// relative to blockHeight, to leave room between rows.
+	blockSpacing = 1.25
+)
+
+// maxObjectWords is the most words wide that slots are drawn. Longer
+// slots are drawn compact, with all their words squeezed into that width,
+// so that one long object doesn't shrink the rest of the heap.
+const maxObjectWords = 16
+
+// slotWords returns how many words wide the slots of b are drawn.
+func slotWords(b *Block) int {
+	return min(b.ElemSize/PointerSize, maxObjectWords)
+}
+
+// compact reports whether the slots of b are drawn compact.
+func compact(b *Block) bool {
+	return b.ElemSize/PointerSize > maxObjectWords
+}
+
+// minLegibleScale is the smallest scale at which blocks are drawn in full
+// detail. Below it, type names and field markers become too small to read,
+// so blocks are drawn compressed instead.
+const minLegibleScale = 0.3
+
+// blockLayout describes where drawGraph places the blocks of a heap.
+type blockLayout struct {
+	columns, rows int
+	colInc        float64 // Horizontal distance between block centers.
+	rowInc        float64 // Vertical distance between block centers.
+	blockWidth    float64
+	margin        float64 // Space left of a block in its column, for its name.
+	scale         float64 // Scale of everything within a block.
+
+	// compressed is true if the heap is too large to draw in full
+	// detail, in which case objects are drawn as plain boxes without
+	// their fields.
+	compressed bool
+}
+
+// center returns the center of the i'th block in area.
+func (l *blockLayout) center(area image.Rectangle, i int) (x, y float64) {
+	col := i % l.columns
+	row := i/l.columns + 1
+	return float64(area.Min.X) + float64(col)*l.colInc + l.margin + l.blockWidth/2, float64(area.Min.Y) + float64(row)*l.rowInc
+}
+
+// layoutBlocks arranges the blocks of h in a grid within area. Each block
+// takes up blockFill of the width of its column, centered, unless that
+// leaves less than labelWidth at scale 1 for its name on the left. It picks
+// the number of columns that lets the blocks be drawn largest, up to
+// maxScale, falling back to a compressed layout if even that is too small
+// to read.
+func layoutBlocks(area image.Rectangle, h *Heap, blockFill, maxScale, labelWidth float64) blockLayout {
+	n := max(len(h.Blocks), 1)
+	natural := naturalBlockWidth(h)
+	var candidates []blockLayout
+	for cols := 1; cols <= n; cols++ {
+		rows := (n + cols - 1) / cols
+		if cols > 1 && (cols-1)*rows >= n {
+			// Same number of rows as with fewer columns, just emptier.
+			continue
+		}
+		l := blockLayout{
+			columns: cols,
+			rows:    rows,
+			colInc:  float64(area.Dx() / cols),
+			rowInc:  float64(area.Dy() / (rows + 1)),
+		}
+		right := l.colInc * (1 - blockFill) / 2
+		l.margin = max(right, labelWidth*maxScale)
+		l.blockWidth = l.colInc - l.margin - right
+		candidates = append(candidates, l)
+	}
+
+	var best blockLayout
+	for _, l := range candidates {
+		l.scale = min(maxScale, l.blockWidth/natural, l.rowInc/(blockHeight*blockSpacing))
+		if l.scale > best.scale {
+			best = l
+		}
+	}
+	if best.scale >= minLegibleScale || best.scale == maxScale {
+		return best
+	}
+
+	// Objects are squeezed to fit the width of a compressed block, so
+	// use as few columns as possible while keeping blocks tall enough to
+	// make out.
+	best = blockLayout{}
+	for _, l := range candidates {
+		l.scale = min(maxScale, l.rowInc/(blockHeight*blockSpacing))
+		l.compressed = true
+		if best.columns == 0 || best.scale < minLegibleScale && l.scale > best.scale {
+			best = l
+		}
+	}
+	return best
+}
+
+// blockLabelWidth returns the width at scale 1 of the widest name of a
+// block of h drawn left of it, including the space before the block.
+func blockLabelWidth(c canvas, h *Heap) float64 {
+	must(setFontFace(c, boldFont, theme.Fonts.Block))
+	var widest float64
+	for i := range h.Blocks {
+		w, _ := c.MeasureString(h.Blocks[i].Name())
+		widest = max(widest, w)
+	}
+	return widest + 16
+}
+
+// shownSlots returns how many of the slots b lists are drawn, which is all
+// of them unless b is a size-class span that lists more than fit. A span
+// can list far more slots than fit at the scale the rest of the heap is
+// drawn at, so rather than shrink everything to fit them, the slots past
+// those that fit are elided, though the first is always drawn.
+func (l *blockLayout) shownSlots(b *Block) int {
+	n := len(b.Objects)
+	if l.compressed || b.SizeClass == 0 {
+		return n
+	}
+	slot := float64(slotWords(b)*ptrWordSize + objPadding)
+	room := l.blockWidth/l.scale - objPadding
+	if float64(n)*slot <= room {
+		return n
+	}
+	// Leave a word's width for the ellipsis.
+	return max(int((room-ptrWordSize)/slot), 1)
+}
+
+// objectWidths returns the width of one word of an object in b, and the
+// space between objects.
+func (l *blockLayout) objectWidths(b *Block) (word, gap float64) {
+	squeeze := float64(slotWords(b)) / float64(b.ElemSize/PointerSize)
+	if !l.compressed {
+		return ptrWordSize * l.scale * squeeze, objPadding * l.scale
+	}
+	words := max(len(b.Objects)*slotWords(b), 1)
+	return (l.blockWidth - 2*objPadding*l.scale) / float64(words) * squeeze, 0
+}
+
+// naturalBlockWidth returns how wide the widest block in h is when drawn at
+// scale 1, including padding. Size-class spans count only their first
+// slot, since the rest can be elided.
+func naturalBlockWidth(h *Heap) float64 {
+	widest := float64(objPadding)
+	for i := range h.Blocks {
+		b := &h.Blocks[i]
+		n := len(b.Objects)
+		w := float64(objPadding)
+		if b.SizeClass != 0 && n > 1 {
+			// The rest are elided, after an ellipsis.
+			n = 1
+			w += ptrWordSize
+		}
+		w += float64(n * (slotWords(b)*ptrWordSize + objPadding))
+		widest = math.Max(widest, w)
+	}
+	return widest
+}
+
+// frameSize is the size of frames in pixels, set by -size.
+var frameSize = image.Pt(1920, 1080)
+
+// framePresets are the frame sizes -size accepts by name.
+var framePresets = map[string]image.Point{
+	"720p":     {1280, 720},
+	"1080p":    {1920, 1080},
+	"4k":       {3840, 2160},
+	"portrait": {1080, 1920},
+}
+
+// parseFrameSize parses a frame size given as "WxH" or by preset name.
+func parseFrameSize(s string) (image.Point, error) {
+	if p, ok := framePresets[strings.ToLower(s)]; ok {
+		return p, nil
+	}
+	var p image.Point
+	if n, err := fmt.Sscanf(s, "%dx%d", &p.X, &p.Y); n != 2 || err != nil || fmt.Sprintf("%dx%d", p.X, p.Y) != s {
+		return image.Point{}, fmt.Errorf("bad frame size %q: want WxH, like 1920x1080, or one of 720p, 1080p, 4k, or portrait", s)
+	}
+	if p.X < 320 || p.Y < 320 {
+		return image.Point{}, fmt.Errorf("frame size %q is too small: want at least 320x320", s)
+	}
+	return p, nil
+}
+
+// portrait reports whether c is taller than it is wide, in which case
+// frames are laid out top to bottom instead of side by side.
+func portrait(c canvas) bool {
+	return c.Height() > c.Width()
+}
+
+// frameScale returns how much to scale the sizes in this package, which
+// are given for a 1920x1080 frame, to fit c. Portrait frames are scaled to
+// their width, so that a 1080x1920 frame has text as large as a 1920x1080
+// one.
+func frameScale(c canvas) float64 {
+	w, h := float64(c.Width()), float64(c.Height())
+	if portrait(c) {
+		return w / 1080
+	}
+	return min(w/1920, h/1080)
+}
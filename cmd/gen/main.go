@@ -5,51 +5,167 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"image"
 	"image/color"
+	"io"
 	"iter"
 	"log"
 	"math"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/fogleman/gg"
-	"github.com/golang/freetype/truetype"
-	"golang.org/x/image/font"
+)
+
+var (
+	formatFlag   = "png"
+	scenarioFlag = ""
+	captionsFlag = false
+	holdFlag     = time.Second
+	worklistFlag = false
+	tweenFlag    = 0
+	compareFlag  = false
+	straightFlag = false
+	addrFlag     = false
+	fieldsFlag   = false
+	gogcFlag     = 100
+	patternsFlag = false
 )
 
 func main() {
-	ms := NewMarkSweep(makeHeap())
-	i := 0
-	for s := range ms.Mark() {
-		fname := fmt.Sprintf("./img/marksweep-%03d.png", i)
-		fmt.Println("generating", fname)
-		must(Draw(s).SavePNG(fname))
-		i++
-	}
-	Sweep(ms)
-	fname := fmt.Sprintf("./img/marksweep-%03d.png", i)
-	fmt.Println("generating", fname)
-	must(Draw(ms).SavePNG(fname))
-
-	gt := NewGreenTea(makeHeap())
-	i = 0
-	for s := range gt.Mark() {
-		fname := fmt.Sprintf("./img/greentea-%03d.png", i)
+	log.SetFlags(0)
+	log.SetPrefix("gen: ")
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "serve":
+			serveMain(os.Args[2:])
+			return
+		case "edit":
+			editMain(os.Args[2:])
+			return
+		}
+	}
+	registerCommonFlags(flag.CommandLine)
+	flag.Func("format", "output format: png, svg, or html (a self-contained page with every frame) (default png)", func(v string) error {
+		if v != "png" && v != "svg" && v != "html" {
+			return fmt.Errorf("bad format %q: want png, svg, or html", v)
+		}
+		formatFlag = v
+		return nil
+	})
+	htmlFrames := "svg"
+	flag.Func("html-frames", "how to embed frames with -format html: svg or png (default svg)", func(v string) error {
+		if v != "png" && v != "svg" {
+			return fmt.Errorf("bad frame format %q: want svg or png", v)
+		}
+		htmlFrames = v
+		return nil
+	})
+	subtitles := flag.Bool("subtitles", false, "also write SRT and WebVTT narration tracks for each run")
+	flag.Parse()
+
+	runs, err := recordAll(heapSource())
+	must(err)
+	if formatFlag == "html" {
+		fname := "./img/visuals.html"
 		fmt.Println("generating", fname)
-		must(Draw(s).SavePNG(fname))
-		i++
+		must(saveBundle(fname, runs, htmlFrames))
+	} else {
+		for _, r := range runs {
+			for i, f := range r.Frames {
+				fname := fmt.Sprintf("./img/%s-%03d.%s", r.Name, i, formatFlag)
+				fmt.Println("generating", fname)
+				must(saveFrame(fname, f))
+			}
+		}
+	}
+	if *subtitles {
+		for _, r := range runs {
+			must(saveSubtitles("./img/"+r.Name, r))
+		}
+	}
+}
+
+// registerCommonFlags registers flags shared by all subcommands, which
+// control the input heap and how frames are rendered.
+func registerCommonFlags(fs *flag.FlagSet) {
+	fs.StringVar(&scenarioFlag, "scenario", scenarioFlag, "scenario file describing the heap (default: built-in example)")
+	fs.BoolVar(&captionsFlag, "captions", captionsFlag, "burn narration captions into the bottom of each frame")
+	fs.DurationVar(&holdFlag, "hold", holdFlag, "minimum time to hold each frame when playing")
+	fs.BoolVar(&worklistFlag, "worklist", worklistFlag, "show a panel with the contents of the work list in order")
+	fs.IntVar(&tweenFlag, "tween", tweenFlag, "number of in-between frames animating each change to the work list panel")
+	fs.Func("theme", "color theme: light, dark, deuteranopia, or a JSON theme file (default light)", func(v string) error {
+		t, err := loadTheme(v)
+		theme = t
+		return err
+	})
+	fs.BoolVar(&patternsFlag, "patterns", patternsFlag, "hatch queued and active objects so they can be told apart without color, with any theme")
+	fs.Func("font", "TrueType `file` to draw labels with (default: embedded Roboto Mono)", fontFlag(monoFont))
+	fs.Func("bold-font", "TrueType `file` to draw active labels with (default: embedded Go Mono Bold)", fontFlag(boldFont))
+	fs.Func("italic-font", "TrueType `file` to draw small print with (default: embedded Go Mono Italic)", fontFlag(italicFont))
+	fs.Func("caption-font", "TrueType `file` to draw captions with (default: embedded Go Regular)", fontFlag(captionFont))
+	fs.Func("size", "frame `size` in pixels: WxH, or 720p, 1080p, 4k, or portrait (default 1920x1080)", func(v string) error {
+		p, err := parseFrameSize(v)
+		frameSize = p
+		return err
+	})
+	fs.BoolVar(&straightFlag, "straight", straightFlag, "draw pointers as straight lines instead of routing them around blocks")
+	fs.BoolVar(&addrFlag, "addresses", addrFlag, "label objects with their addresses and pointer fields with their offsets and values")
+	fs.BoolVar(&fieldsFlag, "fields", fieldsFlag, "label words with their field names from the heap's type declarations, in place of offsets with -addresses")
+	fs.Func("oblet-size", "size in `bytes` of the oblets that large objects are split into for scanning (default 2048; the runtime's are 128 KiB)", parseObletSize)
+	fs.BoolVar(&compareFlag, "compare", compareFlag, "also generate a run showing both collectors side by side, in lockstep by units of work")
+	fs.Func("gogc", "`percent` the heap may grow past what's left in use after a cycle before the next starts, for scenarios with a script (default 100)", parseGOGC)
+}
+
+// heapSource returns a function producing a fresh heap and roots for
+// each run, from -scenario if set.
+func heapSource() func() ([]Root, *Heap) {
+	if scenarioFlag == "" {
+		return makeHeap
+	}
+	sc, err := loadScenario(scenarioFlag)
+	must(err)
+	return func() ([]Root, *Heap) {
+		roots, heap, err := sc.Build()
+		must(err)
+		return roots, heap
+	}
+}
+
+func saveFrame(fname string, fr Frame) error {
+	f, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	if err := encodeFrame(f, formatFlag, fr); err != nil {
+		// Don't leave a partial frame behind.
+		f.Close()
+		os.Remove(fname)
+		return err
 	}
-	Sweep(gt)
-	fname = fmt.Sprintf("./img/greentea-%03d.png", i)
-	fmt.Println("generating", fname)
-	must(Draw(gt).SavePNG(fname))
+	return f.Close()
+}
+
+// encodeFrame renders f and writes it to w in the given format.
+func encodeFrame(w io.Writer, format string, f Frame) error {
+	switch format {
+	case "png":
+		return Draw(f).EncodePNG(w)
+	case "svg":
+		_, err := DrawSVG(f).WriteTo(w)
+		return err
+	}
+	return fmt.Errorf("unknown format %q", format)
 }
 
 func makeHeap() ([]Root, *Heap) {
 	roots := []Root{
-		{"var x *T", 2},
-		{"var y *T", 6},
+		{Name: "var x *T", Pointer: 2},
+		{Name: "var y *T", Pointer: 6},
 	}
 	heap := &Heap{
 		Objects: []Object{
@@ -74,7 +190,12 @@ func makeHeap() ([]Root, *Heap) {
 			Blk(0xc000, 16, Free, Free, 6, 3, 13, Free, Free),
 			Blk(0xd000, 32, Free, 10, Free, 11),
 		},
+		Types: []TypeDecl{
+			{"T", "struct{children *[4]*T; value int}"},
+		},
 	}
+	must(heap.applyTypes())
+	heap.initAllocBits()
 	return roots, heap
 }
 
@@ -92,9 +213,19 @@ type gcStateScanned interface {
 	Scanned(Pointer) bool
 }
 
+type gcStateWorkList interface {
+	// WorkLists returns the collector's work lists, in the order it
+	// takes work from them.
+	WorkLists() []WorkList
+}
+
+// Sweep frees the objects s didn't mark. As in the runtime, each block's
+// mark bits become its alloc bits, so allocation starts over from its
+// first slot and finds the slots of the objects it freed.
 func Sweep(s gcState) {
 	for i := range s.Heap().Blocks {
 		b := &s.Heap().Blocks[i]
+		b.setAllocBits(s.Marked)
 		for j, p := range b.Objects {
 			if s.Marked(p) {
 				continue
@@ -103,315 +234,601 @@ func Sweep(s gcState) {
 			obj := &s.Heap().Objects[p]
 			for k := range obj.Fields {
 				obj.Fields[k].Pointer = Nil
+				obj.Fields[k].Interior = 0
 			}
 		}
 	}
 }
 
-func Draw(s gcState) *gg.Context {
-	c := gg.NewContext(1920, 1080)
-
-	// Clear.
-	c.SetRGB(1, 1, 1)
-	c.DrawRectangle(0, 0, 1920, 1080)
-	c.Fill()
-
-	info := "type T struct{\n" +
-		"\u2800   children *[4]*T\n" +
-		"\u2800   value    int\n" +
-		"}"
-
-	drawObjGraph(c, info, s)
+func Draw(f Frame) *gg.Context {
+	c := gg.NewContext(frameSize.X, frameSize.Y)
+	drawFrame(c, f)
 	return c
 }
 
-func lighten(c color.RGBA) color.RGBA {
-	c.R = satAdd(c.R, uint8(float64(255-c.R)*0.8))
-	c.G = satAdd(c.G, uint8(float64(255-c.G)*0.8))
-	c.B = satAdd(c.B, uint8(float64(255-c.B)*0.8))
+func DrawSVG(f Frame) *svgCanvas {
+	c := newSVGCanvas(frameSize.X, frameSize.Y)
+	drawFrame(c, f)
 	return c
 }
 
-func lightenLess(c color.RGBA) color.RGBA {
-	c.R = satAdd(c.R, uint8(float64(255-c.R)*0.5))
-	c.G = satAdd(c.G, uint8(float64(255-c.G)*0.5))
-	c.B = satAdd(c.B, uint8(float64(255-c.B)*0.5))
-	return c
-}
+func drawFrame(c canvas, f Frame) {
+	// Clear.
+	c.SetColor(theme.Background)
+	c.DrawRectangle(0, 0, float64(c.Width()), float64(c.Height()))
+	c.Fill()
 
-func satAdd(x, y uint8) uint8 {
-	z := uint16(x) + uint16(y)
-	if z > 255 {
-		return 255
+	info := formatTypes(f.State.Heap().Types)
+
+	scale := frameScale(c)
+	if f.compare != nil {
+		drawCompare(c, info, f.State, f.compare, scale)
+	} else {
+		drawObjGraph(c, info, f.State, f.tween, scale)
+	}
+	if captionsFlag && f.compare == nil {
+		drawCaption(c, image.Rect(0, c.Height()*85/100, c.Width(), c.Height()), f.Caption, scale)
 	}
-	return uint8(z)
 }
 
-func drawObjGraph(c *gg.Context, info string, s gcState) {
-	faded := color.Gray{Y: 153}
-	lightenFaded := color.Gray{Y: 0xbb}
-	selected := color.RGBA{R: 0xcc, G: 0x33, B: 0x11, A: 255}
-	queued := color.RGBA{R: 0x00, G: 0x77, B: 0xbb, A: 255}
+// drawCaption draws text centered in area, which is normally the space
+// drawObjGraph leaves empty at the bottom of the frame.
+func drawCaption(c canvas, area image.Rectangle, text string, scale float64) {
+	padding := 48 * scale
 
-	roots, rootsVisited := s.Roots()
-	h := s.Heap()
-	ctx := s.Context()
+	c.SetColor(theme.Foreground)
+	must(setFontFace(c, captionFont, theme.Fonts.Caption*scale))
+	c.DrawStringWrapped(text, float64(area.Min.X)+padding, float64(area.Min.Y+area.Max.Y)/2, 0, 0.5, float64(area.Dx())-2*padding, 1.25, gg.AlignCenter)
+}
 
+// drawObjGraph draws s with the info and legend panels, with all sizes
+// multiplied by scale. In landscape frames, the panels and roots go in a
+// column on the left; in portrait frames, the panels go across the top.
+func drawObjGraph(c canvas, info string, s gcState, tw *tween, scale float64) {
+	si := func(v int) int { return int(float64(v) * scale) }
 	height := c.Height() * 85 / 100 // Leave bottom 15% empty for closed captioning.
 	split := c.Width() / 4
-	const infoHeight = 224
-	const legendHeight = 256
-	const topPadding = 32
-	sideHeight := c.Height() * 80 / 100
-	infoArea := image.Rect(0, topPadding, split, topPadding+infoHeight)
-	rootsArea := image.Rect(0, infoArea.Max.Y, split, sideHeight-legendHeight)
-	legendArea := image.Rect(0, rootsArea.Max.Y, split, rootsArea.Max.Y+legendHeight)
-	heapArea := image.Rect(split, 0, c.Width(), height)
-
-	c.SetColor(color.Black)
-	must(setFontFace(c, "./RobotoMono-Regular.ttf", 32))
+	infoHeight := si(224)
+	legendHeight := si(256)
+	topPadding := si(32)
+	var infoArea, rootsArea, legendArea, heapArea image.Rectangle
+	if portrait(c) {
+		half := c.Width() / 2
+		infoArea = image.Rect(0, topPadding, half, topPadding+infoHeight)
+		legendArea = image.Rect(half, topPadding, c.Width(), topPadding+legendHeight)
+		top := max(infoArea.Max.Y, legendArea.Max.Y)
+		rootsArea = image.Rect(0, top, split, height)
+		heapArea = image.Rect(split, top, c.Width(), height)
+	} else {
+		sideHeight := c.Height() * 80 / 100
+		infoArea = image.Rect(0, topPadding, split, topPadding+infoHeight)
+		rootsArea = image.Rect(0, infoArea.Max.Y, split, sideHeight-legendHeight)
+		if roots, _ := s.Roots(); info == "" && structuredRoots(roots) {
+			// Stack diagrams need all the room they can get.
+			rootsArea.Min.Y = infoArea.Min.Y
+		}
+		legendArea = image.Rect(0, rootsArea.Max.Y, split, rootsArea.Max.Y+legendHeight)
+		heapArea = image.Rect(split, 0, c.Width(), height)
+	}
+	if ph := pacerHeight(s.Heap(), scale); ph != 0 {
+		pacerArea := image.Rect(heapArea.Min.X, max(topPadding, heapArea.Min.Y), heapArea.Max.X, max(topPadding, heapArea.Min.Y)+ph)
+		heapArea.Min.Y = pacerArea.Max.Y
+		drawPacer(c, pacerArea, s.Heap(), scale)
+	}
+	// Arrows go around the panels, which are inset into their areas.
+	panel := func(area image.Rectangle) rect {
+		return rect{float64(area.Min.X), float64(area.Min.Y), float64(area.Max.X), float64(area.Max.Y)}.inset(16 * scale)
+	}
+	panels := []rect{panel(legendArea)}
+	if info != "" {
+		panels = append(panels, panel(infoArea))
+	}
+	blockFill := 0.85
+	if worklistFlag {
+		workListWidth := si(208)
+		workListArea := image.Rect(c.Width()-workListWidth, max(topPadding, heapArea.Min.Y), c.Width(), height)
+		heapArea.Max.X = workListArea.Min.X
+		drawWorkList(c, workListArea, s, tw, scale)
+		panels = append(panels, panel(workListArea))
+
+		// Make up for the space taken by the work list panel.
+		blockFill = 1 - 96*scale/float64(heapArea.Dx())
+	}
 
 	c.SetLineCapButt()
 	c.SetLineJoin(gg.LineJoinRound)
 
-	// Legend.
+	drawLegend(c, legendArea, scale)
+	drawInfo(c, infoArea, info, scale)
+	drawGraph(c, rootsArea, heapArea, panels, blockFill, scale, s)
+}
+
+// drawLegend draws the key to the colors used for objects into area.
+func drawLegend(c canvas, area image.Rectangle, scale float64) {
+	x, y := float64(area.Min.X), float64(area.Min.Y)
+	sz := func(v float64) float64 { return v * scale }
 
-	c.SetLineWidth(4.0)
-	c.DrawRectangle(float64(legendArea.Min.X+16), float64(legendArea.Min.Y+16), float64(legendArea.Dx()-32), float64(legendArea.Dy()-32))
+	c.SetColor(theme.Foreground)
+	must(setFontFace(c, monoFont, sz(theme.Fonts.Legend)))
+
+	c.SetDash()
+	c.SetLineWidth(sz(theme.Strokes.Panel))
+	c.DrawRectangle(x+sz(16), y+sz(16), float64(area.Dx())-sz(32), float64(area.Dy())-sz(32))
 	c.Stroke()
 
-	c.SetLineWidth(3.0)
+	c.SetLineWidth(sz(theme.Strokes.Legend))
 
-	c.SetColor(faded)
-	c.DrawRectangle(float64(legendArea.Min.X+32), float64(legendArea.Min.Y+48), 16, 16)
+	c.SetColor(theme.Faded)
+	c.DrawRectangle(x+sz(32), y+sz(48), sz(16), sz(16))
 	c.Stroke()
-	c.DrawStringAnchored("not visited", float64(legendArea.Min.X)+64, float64(legendArea.Min.Y)+50, 0, 0.5)
+	c.DrawStringAnchored("not visited", x+sz(64), y+sz(50), 0, 0.5)
 
-	c.SetColor(lighten(queued))
-	c.DrawRectangle(float64(legendArea.Min.X+32), float64(legendArea.Min.Y+96), 16, 16)
+	c.SetColor(lighten(theme.Queued))
+	c.DrawRectangle(x+sz(32), y+sz(96), sz(16), sz(16))
 	c.Fill()
-	c.SetColor(queued)
-	c.DrawRectangle(float64(legendArea.Min.X+32), float64(legendArea.Min.Y+96), 16, 16)
+	drawPattern(c, x+sz(32), y+sz(96), sz(16), sz(16), scale*0.6, false)
+	c.SetLineWidth(sz(theme.Strokes.Legend))
+	c.SetColor(theme.Queued)
+	c.DrawRectangle(x+sz(32), y+sz(96), sz(16), sz(16))
 	c.Stroke()
-	c.DrawStringAnchored("on work list", float64(legendArea.Min.X)+64, float64(legendArea.Min.Y)+98, 0, 0.5)
+	c.DrawStringAnchored("on work list", x+sz(64), y+sz(98), 0, 0.5)
 
-	c.SetColor(lighten(selected))
-	c.DrawRectangle(float64(legendArea.Min.X+32), float64(legendArea.Min.Y+144), 16, 16)
+	c.SetColor(lighten(theme.Active))
+	c.DrawRectangle(x+sz(32), y+sz(144), sz(16), sz(16))
 	c.Fill()
-	c.SetColor(selected)
-	c.DrawRectangle(float64(legendArea.Min.X+32), float64(legendArea.Min.Y+144), 16, 16)
+	drawPattern(c, x+sz(32), y+sz(144), sz(16), sz(16), scale*0.6, true)
+	c.SetLineWidth(sz(theme.Strokes.Legend))
+	c.SetColor(theme.Active)
+	c.DrawRectangle(x+sz(32), y+sz(144), sz(16), sz(16))
 	c.Stroke()
-	c.DrawStringAnchored("active", float64(legendArea.Min.X)+64, float64(legendArea.Min.Y)+146, 0, 0.5)
+	c.DrawStringAnchored("active", x+sz(64), y+sz(146), 0, 0.5)
 
-	c.SetColor(lightenFaded)
-	c.DrawRectangle(float64(legendArea.Min.X+32), float64(legendArea.Min.Y+192), 16, 16)
+	c.SetColor(theme.Visited)
+	c.DrawRectangle(x+sz(32), y+sz(192), sz(16), sz(16))
 	c.Fill()
-	c.SetColor(color.Black)
-	c.DrawRectangle(float64(legendArea.Min.X+32), float64(legendArea.Min.Y+192), 16, 16)
+	c.SetColor(theme.Foreground)
+	c.DrawRectangle(x+sz(32), y+sz(192), sz(16), sz(16))
 	c.Stroke()
-	c.DrawStringAnchored("visited", float64(legendArea.Min.X)+64, float64(legendArea.Min.Y)+194, 0, 0.5)
+	c.DrawStringAnchored("visited", x+sz(64), y+sz(194), 0, 0.5)
+}
+
+// drawInfo draws a box containing info into area, shrinking the text if
+// needed to fit. It draws nothing if info is empty.
+func drawInfo(c canvas, area image.Rectangle, info string, scale float64) {
+	if info == "" {
+		return
+	}
+	x, y := float64(area.Min.X), float64(area.Min.Y)
+	sz := func(v float64) float64 { return v * scale }
+
+	size := sz(theme.Fonts.Info)
+	must(setFontFace(c, monoFont, size))
+	var textWidth float64
+	for _, line := range strings.Split(info, "\n") {
+		w, _ := c.MeasureString(line)
+		textWidth = max(textWidth, w)
+	}
+
+	// The text is inset 16 inside a panel that's inset 16 into area, at
+	// the bottom as well as the top. Text doesn't shrink exactly with its
+	// font size, so it may still wrap; its height is measured from the
+	// wrapped lines at each size until it fits.
+	width, height := float64(area.Dx())-sz(64), float64(area.Dy())-sz(64)
+	fit := min(1, width/textWidth)
+	for range 10 {
+		must(setFontFace(c, monoFont, size*fit))
+		_, lineHeight := c.MeasureString("M")
+		textHeight := (float64(len(wordWrap(c, info, width)))*1.25 - 0.25) * lineHeight
+		if textHeight <= height {
+			break
+		}
+		fit *= height / textHeight
+	}
 
-	// Info.
-	c.SetLineWidth(4.0)
-	c.SetColor(color.Black)
-	c.DrawRectangle(float64(infoArea.Min.X+16), float64(infoArea.Min.Y+16), float64(infoArea.Dx()-32), float64(infoArea.Dy()-32))
+	c.SetDash()
+	c.SetLineWidth(sz(theme.Strokes.Panel))
+	c.SetColor(theme.Foreground)
+	c.DrawRectangle(x+sz(16), y+sz(16), float64(area.Dx())-sz(32), float64(area.Dy())-sz(32))
 	c.Stroke()
 
-	c.DrawStringWrapped(info, float64(infoArea.Min.X+32), float64(infoArea.Min.Y+32), 0, 0, float64(infoArea.Dx()-64), 1.25, gg.AlignLeft)
+	c.DrawStringWrapped(info, x+sz(32), y+sz(32), 0, 0, float64(area.Dx())-sz(64), 1.25, gg.AlignLeft)
+}
 
-	must(setFontFace(c, "./RobotoMono-Regular.ttf", 36))
+// drawGraph draws the roots and heap of s into rootsArea and heapArea
+// respectively, along with the pointers between them, which go around the
+// panels drawn beside them. Blocks take up
+// blockFill of the width of their column of heapArea, and all other sizes
+// are multiplied by scale, or less if needed to fit the heap.
+func drawGraph(c canvas, rootsArea, heapArea image.Rectangle, panels []rect, blockFill, scale float64, s gcState) {
+	roots, rootsVisited := s.Roots()
+	h := s.Heap()
+	ctx := s.Context()
+	sz := func(v float64) float64 { return v * scale }
 
-	const ptrWordSize = 64
+	dotRadius := sz(10)
 
 	var rootAnchors []image.Point
-	for i := range roots {
-		const padding = 16
+	if structuredRoots(roots) {
+		rootAnchors = drawRootSets(c, rootsArea, roots, rootsVisited, ctx, scale)
+	} else {
+		for i := range roots {
+			padding := sz(16)
+
+			r := &roots[i]
+			font := monoFont
+			if ctx.Root >= 0 && i == ctx.Root {
+				font = boldFont
+				c.SetColor(theme.Active)
+			} else if i < rootsVisited {
+				c.SetColor(theme.Foreground)
+			} else {
+				c.SetColor(theme.Queued)
+			}
 
-		r := &roots[i]
-		if ctx.Root >= 0 && i == ctx.Root {
-			c.SetColor(selected)
-		} else if i < rootsVisited {
-			c.SetColor(color.Black)
-		} else {
-			c.SetColor(queued)
-		}
+			must(setFontFace(c, font, sz(theme.Fonts.Root)))
+			inc := rootsArea.Dy() / (len(roots) + 1)
+			anchor := image.Pt(rootsArea.Min.X+rootsArea.Dx()*3/4, rootsArea.Min.Y+inc*(i+1))
+			c.DrawStringAnchored(r.Name, float64(anchor.X)-padding, float64(anchor.Y)-sz(4), 1, 0.5)
 
-		inc := rootsArea.Dy() / (len(roots) + 1)
-		anchor := image.Pt(rootsArea.Min.X+rootsArea.Dx()*3/4, rootsArea.Min.Y+inc*(i+1))
-		c.DrawStringAnchored(r.Name, float64(anchor.X)-padding, float64(anchor.Y)-4, 1, 0.5)
+			if ctx.Root >= 0 && i == ctx.Root {
+				c.SetColor(theme.Active)
+			} else if i < rootsVisited {
+				c.SetColor(theme.Foreground)
+			} else {
+				c.SetColor(theme.Faded)
+			}
 
-		if ctx.Root >= 0 && i == ctx.Root {
-			c.SetColor(selected)
-		} else if i < rootsVisited {
-			c.SetColor(color.Black)
-		} else {
-			c.SetColor(faded)
+			anchor.X += int(padding)
+			c.DrawCircle(float64(anchor.X), float64(anchor.Y), dotRadius)
+			c.Fill()
+			rootAnchors = append(rootAnchors, anchor)
 		}
-
-		anchor.X += padding
-		c.DrawCircle(float64(anchor.X), float64(anchor.Y), ptrWordSize/6)
-		c.Fill()
-		rootAnchors = append(rootAnchors, anchor)
 	}
 
-	c.SetColor(color.Black)
-	must(setFontFace(c, "./RobotoMono-Regular.ttf", 28))
+	c.SetColor(theme.Foreground)
+
+	// Everything in the heap is drawn at the layout's scale, which may be
+	// smaller than that of the roots.
+	l := layoutBlocks(heapArea, h, blockFill, scale, blockLabelWidth(c, h))
+	bs := func(v float64) float64 { return v * l.scale }
+	blockWidth := l.blockWidth
+	blockHeight := bs(blockHeight)
+	objHeight := bs(ptrWordSize)
+	dotRadius = min(dotRadius, bs(10))
+
+	var retained Set[Pointer]
+	for _, p := range falselyRetained(s) {
+		retained.Add(p)
+	}
 
-	const blockColumns = 1
-	const blockHeight = 128
+	// Pointer fields are marked with a dot, which moves down to make room
+	// for labels when showing addresses.
+	fieldDotY, fieldDotRadius := objHeight/2, dotRadius
+	if (addrFlag || fieldsFlag) && !l.compressed {
+		fieldDotY, fieldDotRadius = objHeight*3/4, min(dotRadius, objHeight/8)
+	}
 
-	blockWidth := float64(heapArea.Dx()/blockColumns) * 0.85
-	blockRows := (len(h.Blocks) + blockColumns - 1) / blockColumns
-	blockColInc := float64(heapArea.Dx() / blockColumns)
-	blockRowInc := float64(heapArea.Dy() / (blockRows + 1))
+	// Oblets of split objects on work lists, which are drawn queued
+	// separately from the rest of their object.
+	queuedOblets := make(map[WorkItem]bool)
+	if wl, ok := s.(gcStateWorkList); ok {
+		for _, list := range wl.WorkLists() {
+			for _, it := range list.Items {
+				if it.Block == nil && it.Offset != 0 {
+					queuedOblets[it] = true
+				}
+			}
+		}
+	}
 
 	// Draw boxes.
 	ss, hasScanned := s.(gcStateScanned)
 	objBoxes := make(map[Pointer]image.Rectangle)
+	objWords := make(map[Pointer]float64)
+	objBlock := make(map[Pointer]int)
+	var blockRects []rect
 	for i := range h.Blocks {
 		b := &h.Blocks[i]
-		col := i % blockColumns
-		row := (i / blockColumns) + 1
-		cx, cy := float64(heapArea.Min.X)+blockColInc/2+float64(col)*blockColInc, float64(heapArea.Min.Y)+float64(row)*blockRowInc
-
-		must(setFontFace(c, "./RobotoMono-Regular.ttf", 40))
+		cx, cy := l.center(heapArea, i)
 
 		bx := cx - blockWidth/2
 		by := cy - blockHeight/2
+		blockRects = append(blockRects, rect{bx, by, bx + blockWidth, by + blockHeight})
 
 		if ctx.Block == b {
-			c.SetColor(lightenLess(lighten(selected)))
+			c.SetColor(lightenLess(lighten(theme.Active)))
 		} else if s.BlockQueued(b) {
-			c.SetColor(lightenLess(lighten(queued)))
+			c.SetColor(lightenLess(lighten(theme.Queued)))
 		} else {
-			c.SetColor(color.White)
+			c.SetColor(theme.Background)
 		}
-		c.DrawRoundedRectangle(bx, by, blockWidth, blockHeight, 8.0)
+		c.DrawRoundedRectangle(bx, by, blockWidth, blockHeight, bs(8.0))
 		c.Fill()
 
-		c.SetLineWidth(2.0)
+		c.SetLineWidth(bs(theme.Strokes.Block))
 		if ctx.Block == b {
-			c.SetColor(selected)
+			c.SetColor(theme.Active)
 			c.SetDash()
+			if patterns() {
+				c.SetLineWidth(bs(2 * theme.Strokes.Block))
+			}
 		} else if s.BlockQueued(b) {
-			c.SetColor(queued)
+			c.SetColor(theme.Queued)
 			c.SetDash()
 		} else {
-			c.SetColor(color.Black)
-			c.SetDash(4.0)
+			c.SetColor(theme.Foreground)
+			c.SetDash(scaleDashes(theme.Dashes.Block, l.scale)...)
 		}
-		c.DrawRoundedRectangle(bx, by, blockWidth, blockHeight, 8.0)
+		c.DrawRoundedRectangle(bx, by, blockWidth, blockHeight, bs(8.0))
 		c.Stroke()
-		c.DrawStringAnchored(fmt.Sprintf("%X", b.Address>>12), bx-40, cy+12, 0, 0)
-
-		must(setFontFace(c, "./RobotoMono-Regular.ttf", 24))
+		label := b.Name()
+		if ctx.Block == b {
+			must(setFontFace(c, boldFont, bs(theme.Fonts.Block)))
+		} else {
+			must(setFontFace(c, monoFont, bs(theme.Fonts.Block)))
+		}
+		c.DrawStringAnchored(label, bx-bs(16), cy+bs(12), 1, 0)
+
+		// Keep arrows from running through the label.
+		labelWidth, _ := c.MeasureString(label)
+		blockRects[i].minX -= bs(16) + labelWidth
+		// Above the block, the full address, so it's clear which block
+		// a pointer falls in, and what kind of span it is.
+		var header []string
+		if addrFlag {
+			header = append(header, fmt.Sprintf("%#x", b.Address))
+		}
+		shown := l.shownSlots(b)
+		if b.NPages != 0 {
+			header = append(header, spanLabel(b, shown))
+		}
+		if len(header) > 0 {
+			must(setFontFace(c, monoFont, bs(theme.Fonts.Address)))
+			c.DrawStringAnchored(strings.Join(header, "  "), bx+bs(8), by-bs(4), 0, 0)
+		}
 
-		const objPadding = 16
-		baseObjX := bx + objPadding
-		for _, p := range b.Objects {
+		wordWidth, gap := l.objectWidths(b)
+		baseObjX := bx + bs(objPadding)
+		for _, p := range b.Objects[:shown] {
 			obj := &h.Objects[p]
 
 			ox := baseObjX
-			oy := by + blockHeight - objPadding - ptrWordSize
-			width := b.ElemSize / PointerSize * ptrWordSize
-			baseObjX += float64(width + objPadding)
-
-			// Draw object fill.
-			if ctx.Object == p {
-				c.SetColor(lighten(selected))
-			} else if s.Queued(p) {
-				c.SetColor(lighten(queued))
-			} else if s.Marked(p) {
-				c.SetColor(lightenFaded)
+			oy := by + blockHeight - bs(objPadding) - objHeight
+			width := float64(obj.SizeIn(b)/PointerSize) * wordWidth
+			baseObjX += float64(b.ElemSize/PointerSize)*wordWidth + gap
+
+			// Draw object fill. Once a large object is split, each
+			// of its oblets is filled on its own.
+			split := oblets(obj.SizeIn(b))
+			byOblet := split != 0 && ctx.Object == p && ctx.Oblet >= 0
+			for i := 1; i < split && !byOblet; i++ {
+				byOblet = queuedOblets[WorkItem{Object: p, Offset: i * obletSize}]
+			}
+			if byOblet {
+				drawOblets(c, ox, oy, wordWidth, objHeight, l.scale, obj.SizeIn(b), func(i int) (active, queued bool) {
+					return ctx.Object == p && ctx.Oblet == i, queuedOblets[WorkItem{Object: p, Offset: i * obletSize}]
+				})
 			} else {
-				c.SetColor(color.White)
+				if ctx.Object == p {
+					c.SetColor(lighten(theme.Active))
+				} else if s.Queued(p) {
+					c.SetColor(lighten(theme.Queued))
+				} else if s.Marked(p) {
+					c.SetColor(theme.Visited)
+				} else {
+					c.SetColor(theme.Background)
+				}
+				c.DrawRectangle(ox, oy, width, objHeight)
+				c.Fill()
+				if ctx.Object == p || s.Queued(p) {
+					drawPattern(c, ox, oy, width, objHeight, l.scale, ctx.Object == p)
+				}
+			}
+			if split != 0 {
+				drawObletDividers(c, ox, oy, wordWidth, objHeight, l.scale, obj.SizeIn(b))
+			}
+			if len(obj.Tiny) != 0 && !l.compressed {
+				drawTiny(c, ox, oy, wordWidth, objHeight, l.scale, obj, s.Marked(p))
 			}
-			c.DrawRectangle(ox, oy, float64(width), ptrWordSize)
-			c.Fill()
 
-			// Draw object pointer fields.
-			objBoxes[p] = image.Rect(int(ox), int(oy), int(ox)+width, int(oy+ptrWordSize))
+			// Draw object pointer fields, unless there's no room.
+			objBoxes[p] = image.Rect(int(ox), int(oy), int(ox+width), int(oy+objHeight))
+			objWords[p] = wordWidth
+			objBlock[p] = i
+			for _, sc := range obj.Scalars {
+				if l.compressed || compact(b) {
+					break
+				}
+				drawScalar(c, ox+float64(sc.Offset/PointerSize)*wordWidth, oy, wordWidth, objHeight, l.scale, sc, s.Marked(p))
+			}
+			activeEnd := -1 // End of the fields being visited, if any.
+			if ctx.Object == p && ctx.Field >= 0 {
+				activeEnd = fieldStep(h, p, ctx.Field, ctx.Oblet)
+			}
 			for k, f := range obj.Fields {
-				fi := f.Offset / PointerSize
-
-				if s.Marked(p) {
-					c.SetColor(color.Black)
-				} else {
-					c.SetColor(faded)
+				if l.compressed {
+					break
+				}
+				active := k >= ctx.Field && k < activeEnd
+				if compact(b) && f.Pointer == Nil && !active {
+					// There's no room for every word, so only
+					// show where the pointers are.
+					continue
+				}
+				fi := float64(f.Offset / PointerSize)
+
+				if !compact(b) {
+					if s.Marked(p) {
+						c.SetColor(theme.Foreground)
+					} else {
+						c.SetColor(theme.Faded)
+					}
+
+					c.SetDash()
+					c.SetLineWidth(bs(theme.Strokes.Field))
+					c.DrawRectangle(ox+fi*wordWidth, oy, wordWidth, objHeight)
+					c.Stroke()
 				}
 
-				c.SetDash()
-				c.SetLineWidth(2.0)
-				c.DrawRectangle(ox+float64(fi*ptrWordSize), oy, ptrWordSize, ptrWordSize)
-				c.Stroke()
-
-				if ctx.Object == p && ctx.Field >= 0 && ctx.Field == k {
-					c.SetColor(selected)
+				if active {
+					c.SetColor(theme.Active)
 				} else if k < s.FieldsVisited(p) {
-					c.SetColor(color.Black)
+					c.SetColor(theme.Foreground)
 				} else {
-					c.SetColor(faded)
+					c.SetColor(theme.Faded)
 				}
 
-				cx := ox + float64(fi*ptrWordSize) + ptrWordSize/2
-				cy := oy + ptrWordSize/2
-				c.DrawCircle(cx, cy, ptrWordSize/6)
+				cx := ox + fi*wordWidth + wordWidth/2
+				cy := oy + fieldDotY
+				if compact(b) {
+					c.DrawCircle(cx, cy, min(fieldDotRadius, bs(6)))
+					c.Fill()
+					continue
+				}
+				c.DrawCircle(cx, cy, fieldDotRadius)
 				c.Fill()
+
+				// The label at the top and the value under it, leaving
+				// the way down from the dot clear for the arrow.
+				drawWordLabel(c, ox+fi*wordWidth, oy, wordWidth, l.scale, f.Offset, f.Name)
+				if addrFlag {
+					must(setFontFace(c, monoFont, bs(theme.Fonts.Address)))
+					value := "nil"
+					if f.Pointer != Nil {
+						value = fmt.Sprintf("%#x", h.AddressOf(f.Pointer)+uint64(f.Interior))
+					}
+					c.DrawStringAnchored(value, cx, oy+objHeight*0.45, 0.5, 0.5)
+				}
 			}
 
 			// Draw object boundary.
 			if ctx.Object == p {
-				c.SetColor(selected)
+				c.SetColor(theme.Active)
 			} else if s.Queued(p) {
-				c.SetColor(queued)
+				c.SetColor(theme.Queued)
 			} else if s.Marked(p) {
-				c.SetColor(color.Black)
+				c.SetColor(theme.Foreground)
 			} else {
-				c.SetColor(faded)
+				c.SetColor(theme.Faded)
 			}
 			if obj.Type == "<free>" {
-				c.SetDash(2.0)
+				c.SetDash(scaleDashes(theme.Dashes.Free, l.scale)...)
 			} else {
 				c.SetDash()
-				must(setFontFace(c, "./RobotoMono-Regular.ttf", 28))
-				c.DrawStringAnchored(obj.Type, ox, oy-12, 0, 0)
+				if !l.compressed {
+					font := monoFont
+					if ctx.Object == p {
+						font = boldFont
+					}
+					label := obj.Type
+					if compact(b) {
+						label += fmt.Sprintf(" (%d bytes", obj.SizeIn(b))
+						if split != 0 {
+							label += fmt.Sprintf(" in %d oblets", split)
+						}
+						label += ")"
+					}
+					must(setFontFace(c, font, bs(theme.Fonts.Type)))
+					if !addrFlag {
+						// Keep out of the next slot's label.
+						label = fitString(c, label, float64(b.ElemSize/PointerSize)*wordWidth+gap/2)
+					}
+					c.DrawStringAnchored(label, ox, oy-bs(12), 0, 0)
+					if addrFlag {
+						typeWidth, _ := c.MeasureString(label)
+						must(setFontFace(c, monoFont, bs(theme.Fonts.Address)))
+						c.DrawStringAnchored(fmt.Sprintf("%#x", h.AddressOf(p)), ox+typeWidth+bs(8), oy-bs(12), 0, 0)
+					}
+				}
 			}
 
-			c.SetLineWidth(4.0)
-			c.DrawRectangle(ox, oy, float64(width), ptrWordSize)
+			c.SetLineWidth(min(bs(theme.Strokes.Object), width/4))
+			c.DrawRectangle(ox, oy, width, objHeight)
 			c.Stroke()
+			if obj.Fresh {
+				drawFresh(c, ox, oy, width, l.scale)
+			}
+			if retained.Has(p) {
+				// Garbage kept alive by a word that isn't a pointer.
+				c.SetColor(theme.Active)
+				c.SetDash(scaleDashes(theme.Dashes.Conservative, l.scale)...)
+				c.DrawRectangle(ox-bs(4), oy-bs(4), width+bs(8), objHeight+bs(8))
+				c.Stroke()
+				c.SetDash()
+			}
+			if split != 0 && ctx.Object == p && ctx.Oblet >= 0 {
+				// Outline the oblet being scanned.
+				lo := ctx.Oblet * obletSize
+				hi := min(lo+obletSize, obj.SizeIn(b))
+				c.SetColor(theme.Active)
+				c.SetLineWidth(bs(2 * theme.Strokes.Object))
+				c.DrawRectangle(ox+float64(lo/PointerSize)*wordWidth, oy, float64((hi-lo)/PointerSize)*wordWidth, objHeight)
+				c.Stroke()
+			}
+		}
+
+		if shown < len(b.Objects) {
+			// The slots that don't fit.
+			c.SetColor(theme.Faded)
+			must(setFontFace(c, monoFont, bs(theme.Fonts.Type)))
+			c.DrawStringAnchored("…", baseObjX+bs(ptrWordSize)/2, by+blockHeight-bs(objPadding)-objHeight/2, 0.5, 0.35)
 		}
+		drawPageBoundaries(c, b, shown, bx+bs(objPadding), by, blockHeight, wordWidth, gap, l.scale)
 
 		// Draw metadata bitmaps.
-		c.SetLineWidth(2.0)
+		c.SetLineWidth(bs(theme.Strokes.Field))
 		c.SetDash()
 
-		const bitSize = 12
-		mx, my := bx+blockWidth-16-float64(len(b.Objects))*bitSize, by+16
+		bitSize := min(bs(12), (blockWidth-bs(32))/float64(len(b.Objects)))
+		ax, ay := bx+blockWidth-bs(16)-float64(len(b.Objects))*bitSize, by+bs(16)
+		for j := range b.Objects {
+			if j < len(b.AllocBits) && b.AllocBits[j] {
+				c.SetColor(theme.Foreground)
+			} else {
+				c.SetColor(theme.Background)
+			}
+			c.DrawRectangle(ax, ay, bitSize, bitSize)
+			c.Fill()
+			c.SetColor(theme.Faded)
+			c.DrawRectangle(ax, ay, bitSize, bitSize)
+			c.Stroke()
+			ax += bitSize
+		}
+		if b.FreeIndex <= len(b.Objects) && b.FreeIndex < b.NElems() {
+			// The free index, which allocation picks up from.
+			fx := bx + blockWidth - bs(16) - float64(len(b.Objects)-b.FreeIndex)*bitSize + bitSize/2
+			c.SetColor(theme.Foreground)
+			c.MoveTo(fx-bitSize/2, ay-bs(8))
+			c.LineTo(fx+bitSize/2, ay-bs(8))
+			c.LineTo(fx, ay-bs(2))
+			c.ClosePath()
+			c.Fill()
+		}
+		mx, my := bx+blockWidth-bs(16)-float64(len(b.Objects))*bitSize, ay+bitSize+bs(4)
 		for _, p := range b.Objects {
 			if s.Marked(p) {
-				c.SetColor(color.Black)
+				c.SetColor(theme.Foreground)
 			} else {
-				c.SetColor(color.White)
+				c.SetColor(theme.Background)
 			}
 			c.DrawRectangle(mx, my, bitSize, bitSize)
 			c.Fill()
-			c.SetColor(faded)
+			c.SetColor(theme.Faded)
 			c.DrawRectangle(mx, my, bitSize, bitSize)
 			c.Stroke()
 			mx += bitSize
 		}
 		if hasScanned {
-			sx, sy := bx+blockWidth-16-float64(len(b.Objects))*bitSize, by+32
+			sx, sy := bx+blockWidth-bs(16)-float64(len(b.Objects))*bitSize, my+bitSize+bs(4)
 			for _, p := range b.Objects {
 				if ss.Scanned(p) {
-					c.SetColor(color.Black)
+					c.SetColor(theme.Foreground)
 				} else {
-					c.SetColor(color.White)
+					c.SetColor(theme.Background)
 				}
 				c.DrawRectangle(sx, sy, bitSize, bitSize)
 				c.Fill()
-				c.SetColor(faded)
+				c.SetColor(theme.Faded)
 				c.DrawRectangle(sx, sy, bitSize, bitSize)
 				c.Stroke()
 				sx += bitSize
@@ -420,62 +837,261 @@ func drawObjGraph(c *gg.Context, info string, s gcState) {
 	}
 
 	// Draw arrows.
-	c.SetColor(color.Black)
-	c.SetDash()
+	var arrows []arrow
 	for i := range roots {
 		r := &roots[i]
-		dstR, ok := objBoxes[r.Pointer]
-		if !ok {
+		if r.Queued {
+			// The special is gone.
 			continue
 		}
+		targets := []Field{{Pointer: r.Pointer, Interior: r.Interior}}
+		var dash []float64
+		switch {
+		case r.Conservative && r.Scalar:
+			// A scalar that looks like a pointer only gets an arrow
+			// once the collector has taken it for one.
+			if i >= rootsVisited && ctx.Root != i {
+				continue
+			}
+			targets[0].Pointer, targets[0].Interior = conservativeTarget(h, r)
+			dash = theme.Dashes.Conservative
+		case r.Kind == FinalizerRoot:
+			// The object itself isn't a root, so the arrows go to
+			// what it points to, the way the collector marks them.
+			targets = targets[:0]
+			for _, f := range h.Objects[r.Pointer].Fields {
+				if !f.Weak {
+					targets = append(targets, f)
+				}
+			}
+		}
+		var col color.Color
 		if ctx.Root >= 0 && i == ctx.Root {
-			c.SetColor(selected)
+			col = theme.Active
 		} else if i < rootsVisited {
-			c.SetColor(color.Black)
+			col = theme.Foreground
 		} else {
-			c.SetColor(faded)
+			col = theme.Faded
 		}
 		src := rootAnchors[i]
-		dst := minDistPtOnRect(src, dstR, ptrWordSize/3)
-
-		drawArrow(c, float64(src.X), float64(src.Y), float64(dst.X), float64(dst.Y), 3.0)
+		for _, t := range targets {
+			if _, ok := objBoxes[t.Pointer]; !ok {
+				continue
+			}
+			arrows = append(arrows, arrow{gg.Point{X: float64(src.X), Y: float64(src.Y)}, -1, t.Pointer, col, landing(h, t.Pointer, t.Interior, objBoxes, objWords), dash})
+		}
 	}
 	for i := range h.Objects {
 		p := Pointer(i)
 		obj := &h.Objects[p]
-		src := objBoxes[p]
+		src, ok := objBoxes[p]
+		if !ok {
+			// Not drawn.
+			continue
+		}
+		if l.compressed && ctx.Object != p {
+			// Too many arrows to make sense of; only show the
+			// active object's.
+			continue
+		}
 
 		for i, f := range obj.Fields {
-			fi := f.Offset / PointerSize
-			dstR, ok := objBoxes[f.Pointer]
-			if !ok {
+			fi := float64(f.Offset / PointerSize)
+			if _, ok := objBoxes[f.Pointer]; !ok {
 				continue
 			}
 
+			var col color.Color
 			if ctx.Object == p && ctx.Field >= 0 && ctx.Field == i {
-				c.SetColor(selected)
+				col = theme.Active
 			} else if i < s.FieldsVisited(p) {
-				c.SetColor(color.Black)
+				col = theme.Foreground
 			} else {
-				c.SetColor(faded)
+				col = theme.Faded
 			}
 
-			src := image.Pt(src.Min.X+fi*ptrWordSize+ptrWordSize/2, src.Min.Y+ptrWordSize/2)
-			dst := minDistPtOnRect(src, dstR, ptrWordSize/3)
+			var dash []float64
+			if f.Weak {
+				dash = theme.Dashes.Weak
+			}
+			wordWidth := objWords[p]
+			src := image.Pt(src.Min.X+int(fi*wordWidth+wordWidth/2), src.Min.Y+int(fieldDotY))
+			arrows = append(arrows, arrow{gg.Point{X: float64(src.X), Y: float64(src.Y)}, objBlock[p], f.Pointer, col, landing(h, f.Pointer, f.Interior, objBoxes, objWords), dash})
+		}
+	}
 
-			drawArrow(c, float64(src.X), float64(src.Y), float64(dst.X), float64(dst.Y), 3.0)
+	var paths [][]gg.Point
+	if straightFlag {
+		for _, a := range arrows {
+			src := image.Pt(int(a.src.X), int(a.src.Y))
+			dst := minDistPtOnRect(src, objBoxes[a.dst], max(int(objHeight/3), 1))
+			if a.land != 0 {
+				dst = image.Pt(int(a.land), objBoxes[a.dst].Max.Y)
+			}
+			paths = append(paths, []gg.Point{a.src, {X: float64(dst.X), Y: float64(dst.Y)}})
 		}
+	} else {
+		r := &router{
+			blocks:   blockRects,
+			panels:   panels,
+			objects:  make(map[Pointer]rect),
+			objBlock: objBlock,
+			scale:    l.scale,
+			room:     (l.rowInc - blockHeight) / 2,
+		}
+		if l.columns > 1 {
+			r.room = min(r.room, (l.colInc-blockWidth)/2)
+		}
+		for p, b := range objBoxes {
+			r.objects[p] = rect{float64(b.Min.X), float64(b.Min.Y), float64(b.Max.X), float64(b.Max.Y)}
+		}
+		paths = r.route(arrows)
+	}
+	for i, a := range arrows {
+		c.SetColor(a.color)
+		c.SetDash(scaleDashes(a.dash, l.scale)...)
+		drawArrow(c, paths[i], bs(theme.Strokes.Arrow))
+	}
+}
+
+// landing returns the x coordinate at which a pointer to offset off of
+// object p should land on it: under the word it points to, or the tiny
+// allocation starting there. It returns 0 for pointers to the start of an
+// object, which can land anywhere along it.
+func landing(h *Heap, p Pointer, off int, boxes map[Pointer]image.Rectangle, words map[Pointer]float64) float64 {
+	if off == 0 {
+		return 0
+	}
+	x, byteWidth := float64(boxes[p].Min.X), words[p]/PointerSize
+	for _, t := range h.Objects[p].Tiny {
+		if t.Offset == off {
+			return x + (float64(t.Offset)+float64(t.Size)/2)*byteWidth
+		}
+	}
+	return x + (float64(off/PointerSize)+0.5)*words[p]
+}
+
+// spanLabel describes the size class of span b, of which the first shown
+// slots are drawn.
+func spanLabel(b *Block, shown int) string {
+	if b.Large() {
+		return fmt.Sprintf("large object span: %s", plural(b.NPages, "page"))
+	}
+	label := fmt.Sprintf("size class %d: %s of %d bytes in %s",
+		b.SizeClass, plural(b.NElems(), "slot"), b.ElemSize, plural(b.NPages, "page"))
+	if shown < b.NElems() {
+		label += fmt.Sprintf(", first %d shown", shown)
+	}
+	return label
+}
+
+// drawPageBoundaries draws a line across block b, whose slots start at x
+// and whose top is at y, wherever one of its pages ends inside the first
+// shown slots, which are the ones drawn.
+func drawPageBoundaries(c canvas, b *Block, shown int, x, y, height, wordWidth, gap, scale float64) {
+	if b.NPages == 0 {
+		return
+	}
+	c.SetColor(theme.Faded)
+	c.SetLineWidth(scale * theme.Strokes.Field)
+	c.SetDash(scaleDashes(theme.Dashes.Block, scale)...)
+	must(setFontFace(c, monoFont, scale*theme.Fonts.Address))
+	slotWidth := float64(b.ElemSize/PointerSize)*wordWidth + gap
+	for page := 1; page < b.NPages; page++ {
+		off := page * pageSize
+		slot := off / b.ElemSize
+		if slot >= shown {
+			break
+		}
+		px := x + float64(slot)*slotWidth + float64(off%b.ElemSize/PointerSize)*wordWidth
+		if off%b.ElemSize == 0 {
+			// Between two slots.
+			px -= gap / 2
+		}
+		c.MoveTo(px, y)
+		c.LineTo(px, y+height)
+		c.Stroke()
+		label := fmt.Sprintf("page %d", page)
+		if addrFlag {
+			label = fmt.Sprintf("%#x", b.Address+uint64(off))
+		}
+		c.DrawStringAnchored(label, px+scale*4, y+height-scale*3, 0, 0)
 	}
+	c.SetDash()
 }
 
-func drawArrow(c *gg.Context, srcX, srcY, dstX, dstY, width float64) {
+// drawScalar draws the non-pointer word sc of an object as a box at x, y
+// with size w, h, showing its value if it has one.
+func drawScalar(c canvas, x, y, w, h, scale float64, sc Scalar, marked bool) {
+	if marked {
+		c.SetColor(theme.Foreground)
+	} else {
+		c.SetColor(theme.Faded)
+	}
+	c.SetDash()
+	c.SetLineWidth(scale * theme.Strokes.Field)
+	c.DrawRectangle(x, y, w, h)
+	c.Stroke()
+
+	drawWordLabel(c, x, y, w, scale, sc.Offset, sc.Name)
+	if sc.Value != "" {
+		must(setFontFace(c, monoFont, scale*theme.Fonts.Type))
+		c.DrawStringAnchored(sc.Value, x+w/2, y+h/2, 0.5, 0.35)
+	}
+}
+
+// drawWordLabel labels the top of the word of an object at x, y with
+// width w with its field name if -fields is set, or else its offset if
+// -addresses is set.
+func drawWordLabel(c canvas, x, y, w, scale float64, offset int, name string) {
+	var label string
+	switch {
+	case fieldsFlag && name != "":
+		label = name
+	case addrFlag:
+		label = fmt.Sprintf("+%#x", offset)
+	default:
+		return
+	}
+	must(setFontFace(c, monoFont, scale*theme.Fonts.Address))
+	c.DrawStringAnchored(fitString(c, label, w-scale*4), x+w/2, y+scale*4, 0.5, 1)
+}
+
+// fitString returns s, shortened with an ellipsis if needed to fit in
+// width w in the current font.
+func fitString(c canvas, s string, w float64) string {
+	if sw, _ := c.MeasureString(s); sw <= w {
+		return s
+	}
+	r := []rune(s)
+	for len(r) > 1 {
+		r = r[:len(r)-1]
+		if sw, _ := c.MeasureString(string(r) + "…"); sw <= w {
+			break
+		}
+	}
+	return string(r) + "…"
+}
+
+// drawArrow draws a line along path with an arrowhead at the end. Corners
+// along the way are rounded off.
+func drawArrow(c canvas, path []gg.Point, width float64) {
 	c.SetLineWidth(width)
 
-	dist2 := (dstX-srcX)*(dstX-srcX) + (dstY-srcY)*(dstY-srcY)
-	c.MoveTo(srcX, srcY)
+	c.MoveTo(path[0].X, path[0].Y)
+	for i := 1; i < len(path)-1; i++ {
+		a, p, b := path[i-1], path[i], path[i+1]
+		da, db := math.Hypot(a.X-p.X, a.Y-p.Y), math.Hypot(b.X-p.X, b.Y-p.Y)
+		r := min(8*width, da/2, db/2)
+		c.LineTo(p.X+(a.X-p.X)/da*r, p.Y+(a.Y-p.Y)/da*r)
+		c.QuadraticTo(p.X, p.Y, p.X+(b.X-p.X)/db*r, p.Y+(b.Y-p.Y)/db*r)
+	}
+	srcX, srcY := path[len(path)-2].X, path[len(path)-2].Y
+	dstX, dstY := path[len(path)-1].X, path[len(path)-1].Y
 	c.LineTo(dstX, dstY)
 	c.Stroke()
 
+	dist2 := (dstX-srcX)*(dstX-srcX) + (dstY-srcY)*(dstY-srcY)
 	const alBase = 7
 	const th = math.Pi / 8
 	al := alBase * width
@@ -540,37 +1156,3 @@ func must(err error) {
 		log.Fatal(err)
 	}
 }
-
-type fontFaceKey struct {
-	path string
-	size float64
-}
-
-var fontCache = make(map[string]*truetype.Font)
-var faceCache = make(map[fontFaceKey]font.Face)
-
-func setFontFace(c *gg.Context, path string, size float64) error {
-	if f, ok := faceCache[fontFaceKey{path, size}]; ok {
-		c.SetFontFace(f)
-		return nil
-	}
-	if ft, ok := fontCache[path]; ok {
-		f := truetype.NewFace(ft, &truetype.Options{Size: size})
-		faceCache[fontFaceKey{path, size}] = f
-		c.SetFontFace(f)
-		return nil
-	}
-	fontBytes, err := os.ReadFile(path)
-	if err != nil {
-		return err
-	}
-	ft, err := truetype.Parse(fontBytes)
-	if err != nil {
-		return err
-	}
-	fontCache[path] = ft
-	f := truetype.NewFace(ft, &truetype.Options{Size: size})
-	faceCache[fontFaceKey{path, size}] = f
-	c.SetFontFace(f)
-	return nil
-}
@@ -16,7 +16,7 @@ type MarkSweep struct {
 
 	// Mutable.
 	rootsVisited  int
-	stack         []Pointer
+	stack         []WorkItem
 	marked        Set[Pointer]
 	fieldsVisited map[Pointer]int
 	ctx           Context
@@ -52,13 +52,21 @@ func (m *MarkSweep) FieldsVisited(p Pointer) int {
 }
 
 func (m *MarkSweep) Queued(p Pointer) bool {
-	return slices.Contains(m.stack, p)
+	return slices.ContainsFunc(m.stack, func(it WorkItem) bool { return it.Object == p })
 }
 
 func (m *MarkSweep) BlockQueued(_ *Block) bool {
 	return false
 }
 
+func (m *MarkSweep) WorkLists() []WorkList {
+	items := make([]WorkItem, 0, len(m.stack))
+	for _, it := range slices.Backward(m.stack) {
+		items = append(items, it)
+	}
+	return []WorkList{{Name: "work stack", Items: items, LIFO: true}}
+}
+
 func (m *MarkSweep) Context() Context {
 	return m.ctx
 }
@@ -73,19 +81,32 @@ func (m *MarkSweep) mark(yield func(gcState) bool) {
 		return
 	}
 
-	// Roots.
+	// Roots, a set at a time.
 	for r := 0; r < len(m.roots); r++ {
 		m.rootsVisited = r
+		if phase := rootPhase(m.roots, r); phase != "" {
+			m.ctx.Root = -1
+			m.ctx.Phase = phase
+
+			// Yield new phase state.
+			if !yield(m) {
+				return
+			}
+		}
+		if !visitsRoot(&m.roots[r]) {
+			continue
+		}
 		m.ctx.Root = r
-		p := m.roots[r].Pointer
 
 		// Yield selected root state.
 		if !yield(m) {
 			return
 		}
-		if p != Nil && !m.marked.Has(p) {
-			m.marked.Add(p)
-			m.stack = append(m.stack, p)
+		for _, p := range rootTargets(m.heap, &m.roots[r]) {
+			if p != Nil && !m.marked.Has(p) {
+				m.marked.Add(p)
+				m.stack = append(m.stack, WorkItem{Object: p})
+			}
 		}
 
 		// Yield marked object state.
@@ -97,17 +118,31 @@ func (m *MarkSweep) mark(yield func(gcState) bool) {
 	// Finished with roots.
 	m.rootsVisited = len(m.roots)
 	m.ctx.Root = -1
+	m.ctx.Phase = ""
 
 	// Heap.
 	for len(m.stack) != 0 {
-		// Take an object off the stack.
-		p := m.stack[len(m.stack)-1]
+		// Take an object, or an oblet of one, off the stack.
+		it := m.stack[len(m.stack)-1]
 		m.stack = m.stack[:len(m.stack)-1]
+		p := it.Object
 
 		// Iterate over the object's fields and mark new objects,
 		// adding their blocks to the queue if necessary.
 		m.ctx.Object = p
 		m.ctx.Field = -1
+		m.ctx.Oblet = -1
+		if n := oblets(m.heap.SizeOf(p)); n != 0 {
+			// Scan only one oblet of a large object. Scanning the
+			// first pushes the rest, last to first so that they
+			// come off the stack in order.
+			m.ctx.Oblet = it.Offset / obletSize
+			if it.Offset == 0 {
+				for i := n - 1; i > 0; i-- {
+					m.stack = append(m.stack, WorkItem{Object: p, Offset: i * obletSize})
+				}
+			}
+		}
 
 		// Yield new active object.
 		if !yield(m) {
@@ -115,8 +150,13 @@ func (m *MarkSweep) mark(yield func(gcState) bool) {
 		}
 
 		obj := &m.heap.Objects[p]
-		for i, f := range obj.Fields {
+		for i := 0; i < len(obj.Fields); i++ {
+			f := obj.Fields[i]
+			if !inOblet(f, m.ctx.Oblet) {
+				continue
+			}
 			m.ctx.Field = i
+			end := fieldStep(m.heap, p, i, m.ctx.Oblet)
 
 			// Yield new active field.
 			if !yield(m) {
@@ -124,12 +164,13 @@ func (m *MarkSweep) mark(yield func(gcState) bool) {
 			}
 
 			fp := f.Pointer
-			if fp == Nil || m.marked.Has(fp) {
-				m.fieldsVisited[p]++
+			if fp == Nil || f.Weak || m.marked.Has(fp) {
+				m.fieldsVisited[p] += end - i
+				i = end - 1
 				continue
 			}
 			m.marked.Add(fp)
-			m.stack = append(m.stack, fp)
+			m.stack = append(m.stack, WorkItem{Object: fp})
 			m.fieldsVisited[p]++
 
 			// Yield new object marked.
@@ -143,9 +184,16 @@ func (m *MarkSweep) mark(yield func(gcState) bool) {
 	m.ctx.Block = nil
 	m.ctx.Object = Nil
 	m.ctx.Field = -1
+	m.ctx.Oblet = -1
 
 	// Yield final state.
 	if !yield(m) {
 		return
 	}
+
+	// Deal with what wasn't marked.
+	if !finishMark(m, &m.ctx, m.marked.Add, yield) {
+		return
+	}
+	m.ctx.Phase = ""
 }
@@ -0,0 +1,354 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"math/bits"
+	"slices"
+	"strconv"
+)
+
+// The mutator and the pacer.
+//
+// A heap with a script is collected more than once. Between GC cycles,
+// the mutator runs its script, allocating objects and writing pointers,
+// and the next cycle starts once allocating would take the heap past its
+// goal. As with GOGC, the goal is set after each cycle to the bytes still
+// in use plus gogcFlag percent more, so a heap with more live data is
+// allowed to grow more before it's collected again.
+
+// Step is one step of a mutator script: allocating an object, or writing
+// a pointer into a root or a field of an object.
+type Step struct {
+	// Alloc is the object to allocate, which takes Size bytes and
+	// becomes the next object in Heap.Objects.
+	Alloc *Object
+	Size  int
+
+	// Otherwise, the step writes Target to root number Root, or if that
+	// is negative, to the field at Offset in Object.
+	Root     int
+	Object   Pointer
+	Offset   int
+	Target   Pointer
+	Interior int // Like Field.Interior.
+}
+
+// maxSmallSize is the size of the largest size class. Larger objects get
+// a span of their own.
+const maxSmallSize = 32768
+
+// sizeClassFor returns the smallest size class that fits size bytes.
+func sizeClassFor(size int) int {
+	for class := 1; class < numSizeClasses; class++ {
+		if classToSize[class] >= size {
+			return class
+		}
+	}
+	return 0
+}
+
+// slotSize returns the size of the slot an object of size bytes takes.
+func slotSize(size int) int {
+	if size > maxSmallSize {
+		return (size + pageSize - 1) / pageSize * pageSize
+	}
+	return classToSize[sizeClassFor(size)]
+}
+
+// InUse returns the number of bytes of h's slots that hold objects.
+func (h *Heap) InUse() int {
+	n := 0
+	for i := range h.Blocks {
+		b := &h.Blocks[i]
+		for _, p := range b.Objects {
+			if p != Free {
+				n += b.ElemSize
+			}
+		}
+	}
+	return n
+}
+
+// heapGoal returns the heap goal for a heap with live bytes in use after
+// a cycle.
+func heapGoal(live int) int {
+	return live + live*gogcFlag/100
+}
+
+func parseGOGC(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return fmt.Errorf("bad GOGC %q: want a percentage of at least 0", s)
+	}
+	gogcFlag = n
+	return nil
+}
+
+// alloc puts obj, which takes size bytes, in the next free slot of the
+// first block for objects of its size class that has one, or for a large
+// object, in the first freed span big enough for it. If there's none, it
+// goes in a new span past the end of the heap. alloc returns obj.
+func (h *Heap) alloc(obj Object, size int) Pointer {
+	p := Pointer(len(h.Objects))
+	h.Objects = append(h.Objects, obj)
+	class := sizeClassFor(size)
+	for i := range h.Blocks {
+		b := &h.Blocks[i]
+		if size > maxSmallSize {
+			// A large object can reuse any freed large-object span
+			// with enough pages.
+			if !b.Large() || b.ElemSize < slotSize(size) {
+				continue
+			}
+		} else if b.ElemSize != classToSize[class] || b.Large() {
+			continue
+		}
+		if j := b.nextFreeIndex(); j < b.NElems() {
+			h.put(b, j, p, size)
+			return p
+		}
+	}
+	b := Span(h.end(), class)
+	if size > maxSmallSize {
+		npages := slotSize(size) / pageSize
+		b = Block{Address: h.end(), ElemSize: npages * pageSize, Objects: []Pointer{Free}, NPages: npages}
+	}
+	b.setAllocBits(nil)
+	h.put(&b, b.nextFreeIndex(), p, size)
+	h.Blocks = append(h.Blocks, b)
+	return p
+}
+
+// put puts p, which takes size bytes, in slot j of b, and records its size
+// if it doesn't fill the slot.
+func (h *Heap) put(b *Block, j int, p Pointer, size int) {
+	for len(b.Objects) <= j {
+		b.Objects = append(b.Objects, Free)
+	}
+	b.Objects[j] = p
+	h.Objects[p].Size = 0
+	if size = (size + PointerSize - 1) / PointerSize * PointerSize; size < b.ElemSize {
+		h.Objects[p].Size = size
+	}
+}
+
+// initAllocBits sets the alloc bits of h's blocks from the slots that hold
+// objects, as if they had just been swept.
+func (h *Heap) initAllocBits() {
+	for i := range h.Blocks {
+		h.Blocks[i].setAllocBits(func(Pointer) bool { return true })
+	}
+}
+
+// setAllocBits sets b's alloc bits for the objects in it that keep reports
+// true for, or none if keep is nil, and starts allocating from its first
+// slot again.
+func (b *Block) setAllocBits(keep func(Pointer) bool) {
+	b.AllocBits = make([]bool, b.NElems())
+	for j, p := range b.Objects {
+		b.AllocBits[j] = p != Free && keep != nil && keep(p)
+	}
+	b.FreeIndex = 0
+	b.refillAllocCache()
+}
+
+// nextFreeIndex returns the index of the next free slot of b and moves
+// b.FreeIndex past it, or returns b.NElems() if b is full, like the
+// runtime's mspan.nextFreeIndex.
+func (b *Block) nextFreeIndex() int {
+	n := b.NElems()
+	for b.FreeIndex < n {
+		z := bits.TrailingZeros64(b.AllocCache)
+		if z == 64 {
+			// The rest of this group of 64 is allocated.
+			b.FreeIndex = (b.FreeIndex + 64) &^ 63
+			b.refillAllocCache()
+			continue
+		}
+		j := b.FreeIndex + z
+		if j >= n {
+			break
+		}
+		b.FreeIndex = j + 1
+		b.AllocCache >>= z + 1
+		if b.FreeIndex%64 == 0 {
+			b.refillAllocCache()
+		}
+		return j
+	}
+	b.FreeIndex = n
+	return n
+}
+
+// refillAllocCache loads b.AllocCache with the inverse of the alloc bits
+// from b.FreeIndex to the end of their group of 64.
+func (b *Block) refillAllocCache() {
+	base := b.FreeIndex &^ 63
+	b.AllocCache = 0
+	for k := range 64 {
+		if base+k >= len(b.AllocBits) || !b.AllocBits[base+k] {
+			b.AllocCache |= 1 << k
+		}
+	}
+	b.AllocCache >>= b.FreeIndex % 64
+}
+
+// end returns the first page-aligned address past all of h's blocks.
+func (h *Heap) end() uint64 {
+	var end uint64
+	for i := range h.Blocks {
+		b := &h.Blocks[i]
+		e := b.SlotAddress(b.NElems())
+		if b.NPages != 0 {
+			e = b.Address + uint64(b.NPages*pageSize)
+		}
+		end = max(end, e)
+	}
+	return (end + pageSize - 1) / pageSize * pageSize
+}
+
+// runScript runs the mutator script of gc's heap, passing each step to add
+// as record does. Whenever an allocation would take the heap past its
+// goal, it first runs a GC cycle: marking with gc, then sweeping. The first
+// goal comes from the initial heap, as if it were all live, and is also the
+// smallest the goal gets, like the runtime's minimum heap size. It stops
+// with an error at a step that uses an object that has been freed, which
+// can depend on when the cycles run.
+func runScript(gc collector, add func(s gcState, sweep bool)) error {
+	h := gc.Heap()
+	roots, _ := gc.Roots()
+	h.Goal = heapGoal(h.InUse())
+	minGoal := h.Goal
+	add(gc, false)
+	for i, st := range h.Script {
+		if st.Alloc != nil && h.InUse()+slotSize(st.Size) > h.Goal {
+			h.Cycle++
+			for s := range gc.Mark() {
+				add(s, false)
+			}
+			Sweep(gc)
+			h.Live = h.InUse()
+			h.Goal = max(heapGoal(h.Live), minGoal)
+			for k := range h.Objects {
+				h.Objects[k].Fresh = false
+			}
+			add(gc, true)
+			gc.Reset()
+		}
+
+		// The mutator can only have pointers to objects that are
+		// still allocated.
+		live := func(p Pointer) bool { return p == Nil || h.BlockOf(p) != nil }
+		switch {
+		case st.Alloc != nil:
+			obj := *st.Alloc
+			obj.Fields = slices.Clone(obj.Fields)
+			obj.Scalars = slices.Clone(obj.Scalars)
+			obj.Fresh = true
+			for _, f := range obj.Fields {
+				if !live(f.Pointer) {
+					return fmt.Errorf("script step %d: allocates %s pointing to an object that was freed", i, obj.Type)
+				}
+			}
+			h.alloc(obj, st.Size)
+		case st.Root >= 0:
+			if !live(st.Target) {
+				return fmt.Errorf("script step %d: writes root %s to point to an object that was freed", i, roots[st.Root].Name)
+			}
+			roots[st.Root].Pointer, roots[st.Root].Interior = st.Target, st.Interior
+		default:
+			if !live(st.Object) || !live(st.Target) {
+				return fmt.Errorf("script step %d: writes a pointer to or into an object that was freed", i)
+			}
+			obj := &h.Objects[st.Object]
+			k := slices.IndexFunc(obj.Fields, func(f Field) bool { return f.Offset == st.Offset })
+			obj.Fields[k].Pointer, obj.Fields[k].Interior = st.Target, st.Interior
+		}
+		h.Steps++
+		add(gc, false)
+	}
+	return nil
+}
+
+// pacerHeight returns the height of the pacer panel for h at scale, which
+// is only drawn for heaps with a script.
+func pacerHeight(h *Heap, scale float64) int {
+	if len(h.Script) == 0 {
+		return 0
+	}
+	return int(64 * scale)
+}
+
+// drawPacer draws the GC cycle count and a bar showing the heap in use
+// against the goal into area, with a tick at the bytes left in use by the
+// last cycle.
+func drawPacer(c canvas, area image.Rectangle, h *Heap, scale float64) {
+	sz := func(v float64) float64 { return v * scale }
+	mid := float64(area.Min.Y+area.Max.Y) / 2
+	left, right := float64(area.Min.X)+sz(16), float64(area.Max.X)-sz(16)
+
+	c.SetDash()
+	c.SetColor(theme.Foreground)
+	must(setFontFace(c, boldFont, sz(theme.Fonts.Legend)))
+	cycle := "before GC"
+	if h.Cycle != 0 {
+		cycle = fmt.Sprintf("GC cycle %d", h.Cycle)
+	}
+	c.DrawStringAnchored(cycle, left, mid, 0, 0.5)
+	cycleWidth, _ := c.MeasureString("GC cycle 00")
+
+	inUse := h.InUse()
+	must(setFontFace(c, monoFont, sz(theme.Fonts.Legend)*0.75))
+	label := fmt.Sprintf("%d of %d B (GOGC=%d)", inUse, h.Goal, gogcFlag)
+	labelWidth, _ := c.MeasureString(label)
+	c.DrawStringAnchored(label, right, mid, 1, 0.5)
+
+	// The bar.
+	x0, x1 := left+cycleWidth+sz(24), right-labelWidth-sz(24)
+	if x1 <= x0 {
+		return
+	}
+	full := float64(max(inUse, h.Goal, 1))
+	at := func(n int) float64 { return x0 + (x1-x0)*float64(n)/full }
+	barHeight := sz(24)
+	c.SetColor(theme.Visited)
+	c.DrawRectangle(x0, mid-barHeight/2, at(inUse)-x0, barHeight)
+	c.Fill()
+	c.SetLineWidth(sz(theme.Strokes.Panel))
+	c.SetColor(theme.Foreground)
+	c.DrawRectangle(x0, mid-barHeight/2, at(int(full))-x0, barHeight)
+	c.Stroke()
+
+	// The goal, and the live heap it came from.
+	c.SetColor(theme.Active)
+	c.MoveTo(at(h.Goal), mid-barHeight)
+	c.LineTo(at(h.Goal), mid+barHeight)
+	c.Stroke()
+	must(setFontFace(c, italicFont, sz(theme.Fonts.Note)))
+	c.DrawStringAnchored("goal", at(h.Goal), mid-barHeight, 0.5, 0)
+	if h.Cycle != 0 {
+		c.SetColor(theme.Foreground)
+		c.MoveTo(at(h.Live), mid-barHeight/2)
+		c.LineTo(at(h.Live), mid+barHeight)
+		c.Stroke()
+		c.DrawStringAnchored("live", at(h.Live), mid+barHeight, 0.5, 1)
+	}
+}
+
+// drawFresh marks the object drawn at x, y with width w as newly
+// allocated, by folding down its top right corner.
+func drawFresh(c canvas, x, y, w, scale float64) {
+	size := min(scale*12, w/3)
+	c.SetDash()
+	c.SetColor(theme.Foreground)
+	c.MoveTo(x+w-size, y)
+	c.LineTo(x+w, y+size)
+	c.LineTo(x+w-size, y+size)
+	c.ClosePath()
+	c.Fill()
+}
@@ -0,0 +1,107 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNextFreeIndex(t *testing.T) {
+	// A span of size class 2 has 512 slots, so eight groups of 64.
+	tests := []struct {
+		name      string
+		allocated func(j int) bool
+		want      []int // Slots handed out, in order.
+	}{
+		{"empty", func(int) bool { return false }, []int{0, 1, 2}},
+		{"every other", func(j int) bool { return j%2 == 0 }, []int{1, 3, 5}},
+		{"first group full", func(j int) bool { return j < 64 }, []int{64, 65}},
+		{"end of first group", func(j int) bool { return j < 62 || j >= 64 && j < 130 }, []int{62, 63, 130, 131}},
+		{"rest of group full", func(j int) bool { return j != 10 && j != 64 && j < 100 }, []int{10, 64, 100}},
+		{"several groups full", func(j int) bool { return j < 200 }, []int{200, 201}},
+		{"only the last", func(j int) bool { return j != 511 }, []int{511, 512}},
+		{"full", func(int) bool { return true }, []int{512, 512}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := Span(0xc000000000, 2)
+			b.AllocBits = make([]bool, b.NElems())
+			for j := range b.AllocBits {
+				b.AllocBits[j] = tt.allocated(j)
+			}
+			b.refillAllocCache()
+			var got []int
+			for range tt.want {
+				got = append(got, b.nextFreeIndex())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("nextFreeIndex = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetAllocBits(t *testing.T) {
+	a, c := Pointer(2), Pointer(3)
+	b := Span(0xc000000000, 2, a, Free, c)
+	b.FreeIndex = 100
+	b.setAllocBits(func(p Pointer) bool { return p == c })
+	if b.FreeIndex != 0 {
+		t.Errorf("FreeIndex = %d, want 0", b.FreeIndex)
+	}
+	var got []int
+	for range 3 {
+		got = append(got, b.nextFreeIndex())
+	}
+	// a wasn't kept, so its slot is free again, unlike c's.
+	if want := []int{0, 1, 3}; !slices.Equal(got, want) {
+		t.Errorf("nextFreeIndex = %v, want %v", got, want)
+	}
+}
+
+func TestAllocSize(t *testing.T) {
+	tests := []struct {
+		size     int
+		elemSize int
+		objSize  int // Zero if the object fills its slot.
+	}{
+		{8, 8, 0},
+		{12, 16, 0},
+		{24, 24, 0},
+		{40, 48, 40},
+		{1000, 1024, 1000},
+		{40000, 40960, 40000},
+	}
+	for _, tt := range tests {
+		h := &Heap{Objects: []Object{Nil: Obj("nil"), Free: Obj("<free>")}}
+		p := h.alloc(Obj("T"), tt.size)
+		if b := h.BlockOf(p); b == nil || b.ElemSize != tt.elemSize {
+			t.Errorf("alloc(%d) put object in %+v, want a block of %d-byte slots", tt.size, b, tt.elemSize)
+		}
+		if got := h.Objects[p].Size; got != tt.objSize {
+			t.Errorf("alloc(%d) set Size = %d, want %d", tt.size, got, tt.objSize)
+		}
+	}
+}
+
+func TestAllocReusesLargeSpan(t *testing.T) {
+	h := &Heap{Objects: []Object{Nil: Obj("nil"), Free: Obj("<free>")}}
+	p := h.alloc(Obj("buf"), 40000)
+	b := h.BlockOf(p)
+	addr := b.Address
+
+	// Free the object, as a sweep would.
+	b.Objects[0] = Free
+	b.setAllocBits(nil)
+
+	p = h.alloc(Obj("buf"), 33000)
+	if len(h.Blocks) != 1 || h.AddressOf(p) != addr {
+		t.Fatalf("alloc put object at %#x in one of %d blocks, want it in the freed span at %#x", h.AddressOf(p), len(h.Blocks), addr)
+	}
+	if got := h.SizeOf(p); got != 33000 {
+		t.Errorf("SizeOf = %d, want 33000", got)
+	}
+}
@@ -0,0 +1,359 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// narrate returns a sentence describing the step from prev to cur, which
+// are consecutive states of the same run. prev is nil for the first step.
+func narrate(prev, cur gcState) string {
+	roots, rootsVisited := cur.Roots()
+	h := cur.Heap()
+	ctx := cur.Context()
+
+	if prev == nil && len(h.Script) != 0 {
+		return sentence(fmt.Sprintf("the mutator starts with %d bytes of heap in use, so with GOGC=%d, the first GC cycle starts once the heap would grow past %d bytes",
+			h.InUse(), gogcFlag, h.Goal))
+	}
+	if prev == nil {
+		n := 0
+		for _, r := range roots {
+			if !r.Scalar && !r.Queued {
+				n++
+			}
+		}
+		return sentence(fmt.Sprintf("marking begins with %s and nothing marked", plural(n, "root")))
+	}
+	pctx := prev.Context()
+	_, prevRootsVisited := prev.Roots()
+
+	var parts []string
+
+	// What the mutator did, and the pacer.
+	ph := prev.Heap()
+	if h.Cycle != ph.Cycle {
+		parts = append(parts, fmt.Sprintf("the next allocation would take the heap past its %d-byte goal, so GC cycle %d begins", ph.Goal, h.Cycle))
+	}
+	if h.Steps != ph.Steps {
+		parts = append(parts, stepDesc(prev, cur, &h.Script[h.Steps-1]))
+	}
+
+	// What became active.
+	if ctx.Phase != "" && ctx.Phase != pctx.Phase {
+		parts = append(parts, ctx.Phase)
+	}
+	if ctx.Root >= 0 && ctx.Root != pctx.Root {
+		parts = append(parts, "visit "+rootDesc(cur, &roots[ctx.Root], true))
+	}
+	if ctx.Block != nil && (pctx.Block == nil || pctx.Block.Address != ctx.Block.Address) {
+		n := 0
+		if ss, ok := cur.(gcStateScanned); ok {
+			for _, p := range ctx.Block.Objects {
+				if cur.Marked(p) && !ss.Scanned(p) {
+					n++
+				}
+			}
+		}
+		parts = append(parts, fmt.Sprintf("dequeue block %s, which has %s to scan", ctx.Block.Name(), plural(n, "marked object")))
+	}
+	if ctx.Object != Nil && (ctx.Object != pctx.Object || ctx.Oblet != pctx.Oblet) {
+		switch {
+		case ctx.Block != nil:
+			parts = append(parts, fmt.Sprintf("scan object %s", describe(h, ctx.Object)))
+		case ctx.Oblet == 0:
+			n := oblets(h.SizeOf(ctx.Object))
+			parts = append(parts, fmt.Sprintf("pop %s off the %s; it's too large to scan at once, so push %s back and scan just the first",
+				describe(h, ctx.Object), workListOf(prev, ctx.Object), plural(n-1, "more oblet")))
+		case ctx.Oblet > 0:
+			parts = append(parts, fmt.Sprintf("pop oblet %d of %s off the %s and scan it", ctx.Oblet, describe(h, ctx.Object), workListOf(prev, ctx.Object)))
+		default:
+			parts = append(parts, fmt.Sprintf("pop %s off the %s and scan it", describe(h, ctx.Object), workListOf(prev, ctx.Object)))
+		}
+	}
+	if ctx.Object != Nil && ctx.Field >= 0 && (ctx.Field != pctx.Field || ctx.Object != pctx.Object) {
+		obj := &h.Objects[ctx.Object]
+		field := fieldLabel(obj, ctx.Field)
+		f := &obj.Fields[ctx.Field]
+		desc := pointsTo(cur, f.Pointer, f.Interior)
+		if end := fieldStep(h, ctx.Object, ctx.Field, ctx.Oblet); end > ctx.Field+1 {
+			field += " and the next " + plural(end-ctx.Field-1, "field")
+			desc = "are nil"
+		}
+		if ctx.Object == pctx.Object {
+			field += " of " + describe(h, ctx.Object)
+		}
+		if f.Weak && f.Pointer != Nil {
+			desc = fmt.Sprintf("is a weak pointer to %s, which doesn't keep it alive, so it isn't followed", describe(h, f.Pointer))
+		}
+		parts = append(parts, field+" "+desc)
+	}
+
+	// What changed.
+	if ctx.Phase == weakPhase {
+		ph := prev.Heap()
+		for i := range h.Objects {
+			for k, f := range h.Objects[i].Fields {
+				if was := ph.Objects[i].Fields[k].Pointer; f.Weak && f.Pointer == Nil && was != Nil {
+					parts = append(parts, fmt.Sprintf("weak pointer %s of %s points to %s, which wasn't marked, so it's cleared",
+						fieldLabel(&h.Objects[i], k), describe(h, Pointer(i)), describe(h, was)))
+				}
+			}
+		}
+	}
+	var resurrected Set[Pointer]
+	prevRoots, _ := prev.Roots()
+	for i := range roots {
+		r := &roots[i]
+		if r.Kind != SpecialRoot || !r.Queued || prevRoots[i].Queued {
+			continue
+		}
+		switch r.Special {
+		case "finalizer":
+			resurrected.Add(r.Object)
+			parts = append(parts, fmt.Sprintf("%s wasn't marked, so its finalizer is queued to run and removed; it's marked again to keep it alive for the finalizer",
+				describe(h, r.Object)))
+		case "cleanup":
+			parts = append(parts, fmt.Sprintf("%s wasn't marked, so its cleanup is queued to run and removed; the cleanup can't reach it, so it can be freed",
+				describe(h, r.Object)))
+		}
+	}
+	for i := range h.Objects {
+		p := Pointer(i)
+		if !cur.Marked(p) || prev.Marked(p) || resurrected.Has(p) {
+			continue
+		}
+		part := describe(h, p) + " is newly marked"
+		b := h.BlockOf(p)
+		bi := blockIndex(h, b)
+		switch {
+		case b != nil && blockQueuedAt(cur, bi) && !blockQueuedAt(prev, bi):
+			part += fmt.Sprintf(" and its block %s enqueued", b.Name())
+		case b != nil && blockQueuedAt(cur, bi):
+			part += fmt.Sprintf(", but its block %s is already queued", b.Name())
+		case b != nil && ctx.Block == b:
+			part += fmt.Sprintf(" in block %s, which is already being scanned", b.Name())
+		case cur.Queued(p):
+			part += " and pushed onto the " + workListOf(cur, p)
+		}
+		if n := len(h.Objects[p].Tiny); n > 1 {
+			part += fmt.Sprintf("; that keeps all %d allocations packed into it alive", n)
+		}
+		parts = append(parts, part)
+	}
+	if freed := freedObjects(prev, cur); len(freed) != 0 {
+		parts = append(parts, fmt.Sprintf("sweep frees %s, making each block's mark bits its alloc bits: %s", plural(len(freed), "unmarked object"), strings.Join(freed, ", ")))
+	}
+	if h.Live != ph.Live || h.Goal != ph.Goal {
+		part := fmt.Sprintf("%d bytes are left in use, so with GOGC=%d, the next cycle starts once the heap would grow past %d bytes", h.Live, gogcFlag, h.Goal)
+		if h.Goal > heapGoal(h.Live) {
+			part = fmt.Sprintf("%d bytes are left in use, so the goal stays at its minimum of %d bytes", h.Live, h.Goal)
+		}
+		parts = append(parts, part)
+	}
+
+	if len(parts) != 0 {
+		return sentence(strings.Join(parts, "; "))
+	}
+
+	// Nothing new became active or marked.
+	switch {
+	case ctx.Object != Nil && ctx.Field >= 0:
+		return sentence(fmt.Sprintf("the target of %s is already marked, so there's nothing to do", fieldLabel(&h.Objects[ctx.Object], ctx.Field)))
+	case ctx.Root >= 0:
+		return sentence(rootDesc(cur, &roots[ctx.Root], false))
+	case rootsVisited == len(roots) && prevRootsVisited == rootsVisited && ctx == Empty:
+		n := 0
+		for i := range h.Objects {
+			if cur.Marked(Pointer(i)) {
+				n++
+			}
+		}
+		desc := fmt.Sprintf("the work list is empty, so marking is complete with %s; everything else is garbage", plural(n, "marked object"))
+		if retained := falselyRetained(cur); len(retained) != 0 {
+			var names []string
+			for _, p := range retained {
+				names = append(names, describe(h, p))
+			}
+			verb := "were"
+			if len(retained) == 1 {
+				verb = "was"
+			}
+			desc += fmt.Sprintf(", though %s %s only marked because of words that look like pointers: %s", plural(len(retained), "object"), verb, strings.Join(names, ", "))
+		}
+		return sentence(desc)
+	}
+	return ""
+}
+
+// blockIndex returns the index of b in h.Blocks, or -1.
+func blockIndex(h *Heap, b *Block) int {
+	for i := range h.Blocks {
+		if &h.Blocks[i] == b {
+			return i
+		}
+	}
+	return -1
+}
+
+// blockQueuedAt reports whether the i'th block of s's heap is queued.
+// States of the same run may have different copies of the heap, so blocks
+// must be compared by index.
+func blockQueuedAt(s gcState, i int) bool {
+	h := s.Heap()
+	return i >= 0 && i < len(h.Blocks) && s.BlockQueued(&h.Blocks[i])
+}
+
+// workListOf returns the name of the work list holding object p in s. If s
+// has only the one work list, it's just "the work list".
+func workListOf(s gcState, p Pointer) string {
+	if wl, ok := s.(gcStateWorkList); ok {
+		if lists := wl.WorkLists(); len(lists) > 1 {
+			for _, list := range lists {
+				for _, it := range list.Items {
+					if it.Block == nil && it.Object == p {
+						return list.Name
+					}
+				}
+			}
+		}
+	}
+	return "work list"
+}
+
+// rootDesc describes root r and what it keeps alive, as a clause if
+// which is set, as in "root x, which points to T at 0xa000".
+func rootDesc(s gcState, r *Root, which bool) string {
+	subject, pred := "root "+rootLabel(r), pointsTo(s, r.Pointer, r.Interior)
+	switch {
+	case r.Kind == FinalizerRoot:
+		subject = "the finalizer on " + describe(s.Heap(), r.Pointer)
+		pred = "keeps everything the object points to alive, but not the object itself, so that it can still become unreachable"
+	case r.Conservative && r.Scalar:
+		subject = "word " + rootLabel(r)
+		if p, off := conservativeTarget(s.Heap(), r); p != Nil {
+			pred = fmt.Sprintf("holds %s; that's not a pointer, but without a stack map for %s the collector can't tell, and it %s", r.Value, r.Func, pointsTo(s, p, off))
+		} else {
+			pred = fmt.Sprintf("holds %s, not the address of any allocated object, so even conservative scanning passes over it", r.Value)
+		}
+	}
+	if which {
+		return subject + ", which " + pred
+	}
+	return subject + " " + pred
+}
+
+// pointsTo describes what a pointer to offset off of object p points to,
+// and whether it's marked.
+func pointsTo(s gcState, p Pointer, off int) string {
+	if p == Nil {
+		return "is nil"
+	}
+	desc := "points to " + describe(s.Heap(), p)
+	if off != 0 {
+		desc = fmt.Sprintf("points %s into %s", plural(off, "byte"), describe(s.Heap(), p))
+		for _, t := range s.Heap().Objects[p].Tiny {
+			if t.Offset == off {
+				desc = fmt.Sprintf("points to the %s at offset %d of %s", t.Type, off, describe(s.Heap(), p))
+			}
+		}
+	}
+	if b := s.Heap().BlockOf(p); addrFlag && b != nil {
+		desc += fmt.Sprintf(" in block %s", b.Name())
+	}
+	if s.Marked(p) {
+		desc += ", which is already marked"
+	}
+	return desc
+}
+
+// stepDesc describes the mutator taking step st of its script, going
+// from prev to cur.
+func stepDesc(prev, cur gcState, st *Step) string {
+	h, ph := cur.Heap(), prev.Heap()
+	switch {
+	case st.Alloc != nil:
+		p := Pointer(len(ph.Objects))
+		b := h.BlockOf(p)
+		desc := "the mutator allocates " + describe(h, p)
+		if len(h.Blocks) > len(ph.Blocks) {
+			desc += fmt.Sprintf(", but no block for %d-byte objects has a free slot, so the heap grows by block %s for it", b.ElemSize, b.Name())
+		} else if b.Large() {
+			desc += fmt.Sprintf(" in block %s, a freed large-object span", b.Name())
+		} else {
+			j := slices.Index(b.Objects, p)
+			from := ph.Blocks[blockIndex(h, b)].FreeIndex
+			desc += fmt.Sprintf(" in slot %d of block %s", j, b.Name())
+			if j > from {
+				desc += fmt.Sprintf(", the first from its free index, %d, whose alloc bit is clear", from)
+			} else {
+				desc += ", the one at its free index"
+			}
+		}
+		return desc + fmt.Sprintf("; %d bytes of the heap's %d-byte goal are now in use", h.InUse(), h.Goal)
+	case st.Root >= 0:
+		roots, _ := cur.Roots()
+		return fmt.Sprintf("the mutator writes root %s, which %s now", rootLabel(&roots[st.Root]), pointsTo(cur, st.Target, st.Interior))
+	}
+	obj := &h.Objects[st.Object]
+	k := slices.IndexFunc(obj.Fields, func(f Field) bool { return f.Offset == st.Offset })
+	return fmt.Sprintf("the mutator writes %s of %s, which %s now", fieldLabel(obj, k), describe(h, st.Object), pointsTo(cur, st.Target, st.Interior))
+}
+
+// fieldLabel returns a name for the k'th pointer field of obj, like
+// "field children" if its type is declared, or "field 1" otherwise.
+func fieldLabel(obj *Object, k int) string {
+	if name := obj.Fields[k].Name; name != "" {
+		return "field " + name
+	}
+	return fmt.Sprintf("field %d", k)
+}
+
+// describe returns a human-readable name for p, like "T at 0xa000".
+func describe(h *Heap, p Pointer) string {
+	return fmt.Sprintf("%s at %#x", h.Objects[p].Type, h.AddressOf(p))
+}
+
+// freedObjects returns descriptions of objects whose slots were freed
+// between prev and cur.
+func freedObjects(prev, cur gcState) []string {
+	var freed []string
+	ph, ch := prev.Heap(), cur.Heap()
+	for i := range ch.Blocks {
+		if i >= len(ph.Blocks) {
+			break
+		}
+		for j, p := range ch.Blocks[i].Objects {
+			if j < len(ph.Blocks[i].Objects) {
+				if pp := ph.Blocks[i].Objects[j]; p == Free && pp != Free {
+					d := describe(ph, pp)
+					if t := ph.Objects[pp].Tiny; len(t) != 0 {
+						d += fmt.Sprintf(" and the %s packed into it", plural(len(t), "allocation"))
+					}
+					freed = append(freed, d)
+				}
+			}
+		}
+	}
+	return freed
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// sentence capitalizes s and terminates it with a period.
+func sentence(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:] + "."
+}
@@ -0,0 +1,108 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// obletSize is the size of the chunks, called oblets, that collectors
+// split large objects into when scanning them from a work list, so that
+// one long scan doesn't hold up other work and the rest of the object can
+// be scanned in parallel. The runtime's oblets are 128 KiB, which would
+// take too many fields to show, so the default here is much smaller. It's
+// set by -oblet-size.
+var obletSize = 2048
+
+func parseObletSize(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 || n%PointerSize != 0 {
+		return fmt.Errorf("bad oblet size %q: want a positive multiple of %d bytes", s, PointerSize)
+	}
+	obletSize = n
+	return nil
+}
+
+// oblets returns the number of oblets an object of size bytes is split
+// into, or 0 if it's small enough to scan whole.
+func oblets(size int) int {
+	if size <= obletSize {
+		return 0
+	}
+	return (size + obletSize - 1) / obletSize
+}
+
+// SizeOf returns the size of object p in bytes.
+func (h *Heap) SizeOf(p Pointer) int {
+	b := h.BlockOf(p)
+	if b == nil {
+		return 0
+	}
+	return h.Objects[p].SizeIn(b)
+}
+
+// inOblet reports whether field f is in the given oblet of its object,
+// which is every field if oblet is -1.
+func inOblet(f Field, oblet int) bool {
+	return oblet < 0 || f.Offset/obletSize == oblet
+}
+
+// fieldStep returns the index of the field after those a collector visits
+// in one step starting at field i of p, in the given oblet as for
+// inOblet. That's just field i, unless p is drawn compact, where nil
+// fields aren't drawn, in which case a run of nil fields is one step.
+func fieldStep(h *Heap, p Pointer, i, oblet int) int {
+	fields := h.Objects[p].Fields
+	b := h.BlockOf(p)
+	if b == nil || !compact(b) || fields[i].Pointer != Nil {
+		return i + 1
+	}
+	end := i + 1
+	for end < len(fields) && fields[end].Pointer == Nil && inOblet(fields[end], oblet) {
+		end++
+	}
+	return end
+}
+
+// drawOblets fills each oblet of an object of size bytes drawn at x, y
+// with words w wide and h high, according to whether state says it's
+// being scanned or waiting on a work list. Other oblets are filled as
+// visited, since only marked objects are split.
+func drawOblets(c canvas, x, y, w, h, scale float64, size int, state func(oblet int) (active, queued bool)) {
+	for i := range oblets(size) {
+		lo := i * obletSize
+		hi := min(lo+obletSize, size)
+		ox, ow := x+float64(lo/PointerSize)*w, float64((hi-lo)/PointerSize)*w
+		active, queued := state(i)
+		switch {
+		case active:
+			c.SetColor(lighten(theme.Active))
+		case queued:
+			c.SetColor(lighten(theme.Queued))
+		default:
+			c.SetColor(theme.Visited)
+		}
+		c.DrawRectangle(ox, y, ow, h)
+		c.Fill()
+		if active || queued {
+			drawPattern(c, ox, y, ow, h, scale, active)
+		}
+	}
+}
+
+// drawObletDividers draws lines between the oblets of an object of size
+// bytes drawn at x, y with words w wide and h high.
+func drawObletDividers(c canvas, x, y, w, h, scale float64, size int) {
+	c.SetColor(theme.Faded)
+	c.SetDash()
+	c.SetLineWidth(scale * theme.Strokes.Field)
+	for off := obletSize; off < size; off += obletSize {
+		dx := x + float64(off/PointerSize)*w
+		c.MoveTo(dx, y)
+		c.LineTo(dx, y+h)
+		c.Stroke()
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"image"
+)
+
+// RootKind is where a root is found.
+type RootKind int
+
+const (
+	// VarRoot is a root that's just a named variable, drawn on its own.
+	VarRoot RootKind = iota
+
+	// StackRoot is a slot in a frame of a goroutine's stack.
+	StackRoot
+
+	// GlobalRoot is a word of a data or BSS segment. Which words hold
+	// pointers is given by the segment's pointer bitmap.
+	GlobalRoot
+
+	// FinalizerRoot is an object with a finalizer. Everything the
+	// object points to is a root, but the object itself isn't, or it
+	// would never become unreachable and be finalized.
+	FinalizerRoot
+
+	// SpecialRoot is the function value of a finalizer or cleanup.
+	SpecialRoot
+)
+
+// structuredRoots reports whether any of roots are in a set, in which case
+// they're drawn as stack diagrams instead of a list of names.
+func structuredRoots(roots []Root) bool {
+	for _, r := range roots {
+		if r.Set != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// rootPhase returns the phase of marking that starts with root r, or ""
+// if r doesn't start one.
+func rootPhase(roots []Root, r int) string {
+	set := roots[r].Set
+	if set == "" || r > 0 && roots[r-1].Set == set {
+		return ""
+	}
+	switch roots[r].Kind {
+	case StackRoot:
+		return fmt.Sprintf("scan %s's stack", set)
+	case GlobalRoot:
+		return fmt.Sprintf("scan the %s segment", set)
+	}
+	return "scan the " + set
+}
+
+// visitsRoot reports whether the collector visits root r while marking,
+// which it doesn't for words it knows hold scalars, or specials that are
+// gone.
+func visitsRoot(r *Root) bool {
+	return (!r.Scalar || r.Conservative) && !r.Queued
+}
+
+// rootTargets returns the objects that root r marks: the one it points
+// to, or seems to if it's scanned conservatively, or for an object with a
+// finalizer, the ones the object points to.
+func rootTargets(h *Heap, r *Root) []Pointer {
+	switch {
+	case r.Conservative:
+		p, _ := conservativeTarget(h, r)
+		return []Pointer{p}
+	case r.Scalar:
+		return nil
+	case r.Kind == FinalizerRoot:
+		var ps []Pointer
+		for _, f := range h.Objects[r.Pointer].Fields {
+			if !f.Weak {
+				ps = append(ps, f.Pointer)
+			}
+		}
+		return ps
+	}
+	return []Pointer{r.Pointer}
+}
+
+// rootLabel describes root r for narration.
+func rootLabel(r *Root) string {
+	if r.Kind == StackRoot {
+		return fmt.Sprintf("%s in %s", r.Name, r.Func)
+	}
+	return r.Name
+}
+
+// A rootRow is one row of a stack diagram.
+type rootRow struct {
+	kind rootRowKind
+	root int // Index of the root the row is for.
+}
+
+type rootRowKind int
+
+const (
+	rowSet   rootRowKind = iota // Title of a set of roots.
+	rowFrame                    // Function of a stack frame.
+	rowWord                     // A word holding a root.
+)
+
+// drawRootSets draws roots into area as stack diagrams. Each set of roots
+// gets a title, each frame of a stack is a box holding its slots, and each
+// word is a row of its own, with a dot for pointers and the pointer bitmap
+// alongside words of global segments. It returns the anchor
+// of each root's arrow.
+func drawRootSets(c canvas, area image.Rectangle, roots []Root, visited int, ctx Context, scale float64) []image.Point {
+	sz := func(v float64) float64 { return v * scale }
+
+	var rows []rootRow
+	for i, r := range roots {
+		newSet := i == 0 || r.Set != roots[i-1].Set
+		if newSet && r.Set != "" {
+			rows = append(rows, rootRow{rowSet, i})
+		}
+		if r.Kind == StackRoot && (newSet || r.Frame != roots[i-1].Frame) {
+			rows = append(rows, rootRow{rowFrame, i})
+		}
+		rows = append(rows, rootRow{rowWord, i})
+	}
+
+	rowHeight := min(sz(32), (float64(area.Dy())-sz(32))/float64(len(rows)))
+	fontSize := min(sz(theme.Fonts.WorkList), rowHeight*0.7)
+	left := float64(area.Min.X) + sz(16)
+	// Leave a lane to the right of the words for arrows to run down.
+	cellWidth := rowHeight * 1.5
+	cellX := float64(area.Min.X) + float64(area.Dx())*5/8 - cellWidth/2
+	cellRight := cellX + cellWidth
+	bitSize := rowHeight * 0.4
+	dotRadius := min(sz(10), rowHeight*0.3)
+	rowY := func(k int) float64 { return float64(area.Min.Y) + sz(16) + float64(k)*rowHeight }
+
+	anchors := make([]image.Point, len(roots))
+	frameTop := 0.0
+	for k, row := range rows {
+		i := row.root
+		r := &roots[i]
+		y := rowY(k)
+		mid := y + rowHeight/2
+		switch row.kind {
+		case rowSet:
+			font := monoFont
+			c.SetColor(theme.Foreground)
+			if phase := rootPhase(roots, i); ctx.Phase == phase {
+				font = boldFont
+				c.SetColor(theme.Active)
+			}
+			must(setFontFace(c, font, fontSize))
+			title := r.Set
+			if r.Kind == StackRoot {
+				title += " stack"
+			}
+			c.DrawStringAnchored(fitString(c, title, cellRight-left), left, mid, 0, 0.5)
+		case rowFrame:
+			frameTop = y
+			c.SetColor(theme.Foreground)
+			must(setFontFace(c, italicFont, fontSize*0.85))
+			label := r.Func
+			if r.Conservative {
+				label += " (no stack map)"
+			}
+			c.DrawStringAnchored(fitString(c, label, cellRight-left-sz(16)), left+sz(16), mid, 0, 0.5)
+		case rowWord:
+			active := ctx.Root == i
+			switch {
+			case r.Scalar || r.Queued:
+				c.SetColor(theme.Faded)
+			case active:
+				c.SetColor(theme.Active)
+			case i < visited:
+				c.SetColor(theme.Foreground)
+			default:
+				c.SetColor(theme.Queued)
+			}
+			font := monoFont
+			if active {
+				font = boldFont
+			}
+			must(setFontFace(c, font, fontSize))
+			nameRight := cellX - sz(8)
+			if r.Kind == GlobalRoot {
+				nameRight -= bitSize + sz(8)
+			}
+			name, value := r.Name, r.Value
+			if w, _ := c.MeasureString(value); r.Scalar && w > cellWidth*1.4 {
+				// Too long to show in the word, like most addresses.
+				name, value = name+" = "+value, ""
+			}
+			c.DrawStringAnchored(fitString(c, name, nameRight-left-sz(16)), nameRight, mid, 1, 0.5)
+
+			// The word itself.
+			c.SetDash()
+			c.SetLineWidth(sz(theme.Strokes.Field))
+			c.SetColor(theme.Faded)
+			c.DrawRectangle(cellX, y, cellWidth, rowHeight)
+			c.Stroke()
+			if r.Kind == GlobalRoot {
+				drawBitmapBit(c, cellX-sz(8)-bitSize, mid-bitSize/2, bitSize, !r.Scalar)
+			}
+			if r.Scalar {
+				c.SetColor(theme.Faded)
+				must(setFontFace(c, monoFont, fontSize*0.7))
+				c.DrawStringAnchored(fitString(c, value, cellWidth), cellX+cellWidth/2, mid, 0.5, 0.5)
+				if r.Conservative {
+					// In case it looks like a pointer.
+					anchors[i] = image.Pt(int(cellRight), int(mid))
+				}
+				break
+			}
+			switch {
+			case active:
+				c.SetColor(theme.Active)
+			case i < visited && !r.Queued:
+				c.SetColor(theme.Foreground)
+			default:
+				c.SetColor(theme.Faded)
+			}
+			c.DrawCircle(cellX+cellWidth/2, mid, dotRadius)
+			if r.Pointer == Nil || r.Queued {
+				c.Stroke()
+				break
+			}
+			c.Fill()
+
+			// Arrows leave from the edge of the word, so they don't
+			// cross the words below on their way.
+			c.SetLineWidth(sz(theme.Strokes.Arrow))
+			c.MoveTo(cellX+cellWidth/2, mid)
+			c.LineTo(cellRight, mid)
+			c.Stroke()
+			anchors[i] = image.Pt(int(cellRight), int(mid))
+		}
+
+		// Close the frame box after its last slot.
+		last := k+1 == len(rows) || rows[k+1].kind != rowWord
+		if r.Kind == StackRoot && row.kind == rowWord && last {
+			c.SetDash()
+			c.SetColor(theme.Foreground)
+			c.SetLineWidth(sz(theme.Strokes.Field))
+			c.DrawRectangle(left+sz(8), frameTop, cellRight+sz(4)-left-sz(8), y+rowHeight-frameTop)
+			c.Stroke()
+		}
+	}
+	return anchors
+}
+
+// drawBitmapBit draws one bit of a pointer bitmap as a square at x, y,
+// filled if set.
+func drawBitmapBit(c canvas, x, y, size float64, set bool) {
+	if set {
+		c.SetColor(theme.Foreground)
+	} else {
+		c.SetColor(theme.Background)
+	}
+	c.DrawRectangle(x, y, size, size)
+	c.Fill()
+	c.SetColor(theme.Faded)
+	c.DrawRectangle(x, y, size, size)
+	c.Stroke()
+}
@@ -0,0 +1,420 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"cmp"
+	"image/color"
+	"math"
+	"slices"
+
+	"github.com/fogleman/gg"
+)
+
+// arrow is a pointer to draw from a root or field to an object.
+type arrow struct {
+	src   gg.Point
+	block int // Index of the block containing src, or -1 for roots.
+	dst   Pointer
+	color color.Color
+
+	// land is the x coordinate at which the arrow lands on dst, if it
+	// points into the middle of it, or 0 to land anywhere along it.
+	land float64
+
+	// dash is the dash pattern of the arrow, at scale 1, for pointers
+	// that aren't ordinary ones, or nil for a solid line.
+	dash []float64
+}
+
+// rect is a rectangle with floating-point coordinates.
+type rect struct {
+	minX, minY, maxX, maxY float64
+}
+
+func (r rect) inset(d float64) rect {
+	return rect{r.minX + d, r.minY + d, r.maxX - d, r.maxY - d}
+}
+
+func (r rect) corners() [4]gg.Point {
+	return [4]gg.Point{{X: r.minX, Y: r.minY}, {X: r.maxX, Y: r.minY}, {X: r.maxX, Y: r.maxY}, {X: r.minX, Y: r.maxY}}
+}
+
+func (r rect) contains(p gg.Point) bool {
+	return p.X > r.minX && p.X < r.maxX && p.Y > r.minY && p.Y < r.maxY
+}
+
+// crosses reports whether the segment from a to b passes through the
+// interior of r.
+func (r rect) crosses(a, b gg.Point) bool {
+	// Liang-Barsky clipping.
+	t0, t1 := 0.0, 1.0
+	dx, dy := b.X-a.X, b.Y-a.Y
+	for _, e := range [4][2]float64{
+		{-dx, a.X - r.minX},
+		{dx, r.maxX - a.X},
+		{-dy, a.Y - r.minY},
+		{dy, r.maxY - a.Y},
+	} {
+		p, q := e[0], e[1]
+		if p == 0 {
+			if q <= 0 {
+				return false
+			}
+			continue
+		}
+		t := q / p
+		if p < 0 {
+			t0 = max(t0, t)
+		} else {
+			t1 = min(t1, t)
+		}
+		if t0 >= t1 {
+			return false
+		}
+	}
+	return true
+}
+
+// router lays out arrows so that they go around blocks and panels instead
+// of through them.
+//
+// Every arrow leaves its block straight down through the bottom edge and
+// lands on the bottom edge of its target object, so it never crosses the
+// objects and labels inside a block. In between, it takes the shortest
+// path around the blocks and panels, found over the visibility graph of
+// their corners, which is built once for all the arrows. Arrows that would
+// otherwise run on top of each other are kept apart by landing at
+// different points along their target, and where they run along the same
+// side of a block, by keeping to different lanes beside it. Interior
+// pointers land under the word they point to instead.
+type router struct {
+	blocks   []rect
+	panels   []rect // Other things to keep arrows out of, like the legend.
+	objects  map[Pointer]rect
+	objBlock map[Pointer]int
+	scale    float64
+
+	// room is how far arrows can stray from a block while leaving a gap
+	// between it and its neighbors.
+	room float64
+}
+
+// Number of lanes around each block and the space between them, at scale 1.
+const (
+	routeLanes   = 4
+	routeMargin  = 6
+	routeLaneGap = 5
+)
+
+// margin returns how far from blocks arrows in the given lane keep.
+func (r *router) margin(lane int) float64 {
+	base := min(routeMargin*r.scale, r.room)
+	gap := max(0, min(routeLaneGap*r.scale, (r.room-base)/(routeLanes-1)))
+	return base + float64(lane)*gap
+}
+
+// route returns the path for each arrow, from its source to where it
+// lands on its target, which must be in r.objects.
+func (r *router) route(arrows []arrow) [][]gg.Point {
+	// Spread out arrows landing on the same object, ordered by where they
+	// come from so they don't cross on the way in.
+	byDst := make(map[Pointer][]int)
+	landing := make([]gg.Point, len(arrows))
+	for i, a := range arrows {
+		if a.land != 0 {
+			landing[i] = gg.Point{X: a.land, Y: r.objects[a.dst].maxY}
+			continue
+		}
+		byDst[a.dst] = append(byDst[a.dst], i)
+	}
+	for dst, idx := range byDst {
+		slices.SortStableFunc(idx, func(i, j int) int {
+			return cmp.Compare(arrows[i].src.X, arrows[j].src.X)
+		})
+		o := r.objects[dst]
+		for k, i := range idx {
+			x := o.minX + (o.maxX-o.minX)*float64(k+1)/float64(len(idx)+1)
+			landing[i] = gg.Point{X: x, Y: o.maxY}
+		}
+	}
+
+	// Route everything in the innermost lane, then move arrows that
+	// share a side of an obstacle out into lanes of their own.
+	margin := r.margin(0)
+	var obstacles []rect
+	for _, b := range r.blocks {
+		obstacles = append(obstacles, b.inset(-margin))
+	}
+	for _, p := range r.panels {
+		obstacles = append(obstacles, p.inset(-margin))
+	}
+	g := newVisGraph(obstacles)
+	routes := make([][]gg.Point, len(arrows))
+	for i, a := range arrows {
+		from := a.src
+		if a.block >= 0 {
+			from = gg.Point{X: a.src.X, Y: obstacles[a.block].maxY}
+		}
+		to := gg.Point{X: landing[i].X, Y: obstacles[r.objBlock[a.dst]].maxY}
+		routes[i] = append([]gg.Point{from}, g.shortestPath(from, to)...)
+	}
+	r.assignLanes(routes, obstacles)
+
+	paths := make([][]gg.Point, len(arrows))
+	for i, a := range arrows {
+		path := append([]gg.Point{a.src}, routes[i]...)
+		paths[i] = dedupPoints(append(path, landing[i]))
+	}
+	return paths
+}
+
+// A sideRun is a segment of a route that runs along a side of an obstacle.
+type sideRun struct {
+	route, seg int     // The segment from point seg to seg+1 of the route.
+	lo, hi     float64 // Where it starts and ends along the side.
+}
+
+// assignLanes gives each segment of routes that runs along a side of one
+// of the obstacles a lane, so that no two overlapping segments along the
+// same side are in the same lane while there are lanes to spare, and moves
+// the segments out to their lanes.
+func (r *router) assignLanes(routes [][]gg.Point, obstacles []rect) {
+	type side struct{ obstacle, edge int }
+	runs := make(map[side][]sideRun)
+	var sides []side
+	for i, route := range routes {
+		for k := 0; k+1 < len(route); k++ {
+			p, q := route[k], route[k+1]
+			j, edge := alongSide(obstacles, p, q)
+			if j < 0 {
+				continue
+			}
+			run := sideRun{route: i, seg: k, lo: min(p.X, q.X), hi: max(p.X, q.X)}
+			if edge == sideLeft || edge == sideRight {
+				run.lo, run.hi = min(p.Y, q.Y), max(p.Y, q.Y)
+			}
+			s := side{j, edge}
+			if runs[s] == nil {
+				sides = append(sides, s)
+			}
+			runs[s] = append(runs[s], run)
+		}
+	}
+
+	// How far to move each point of each route.
+	moves := make([][]gg.Point, len(routes))
+	for i, route := range routes {
+		moves[i] = make([]gg.Point, len(route))
+	}
+	const eps = 0.5
+	for _, s := range sides {
+		rs := runs[s]
+		slices.SortStableFunc(rs, func(a, b sideRun) int {
+			return cmp.Compare(a.lo, b.lo)
+		})
+		// Each lane holds runs one after another, like intervals
+		// packed into tracks. When every lane is taken, the one that
+		// frees up soonest is shared.
+		var ends [routeLanes]float64
+		for i := range ends {
+			ends[i] = math.Inf(-1)
+		}
+		for _, run := range rs {
+			lane := 0
+			for l := range ends {
+				if ends[l] <= run.lo+eps {
+					lane = l
+					break
+				}
+				if ends[l] < ends[lane] {
+					lane = l
+				}
+			}
+			ends[lane] = max(ends[lane], run.hi)
+			d := r.margin(lane) - r.margin(0)
+			for _, k := range []int{run.seg, run.seg + 1} {
+				m := &moves[run.route][k]
+				switch s.edge {
+				case sideTop:
+					m.Y = min(m.Y, -d)
+				case sideBottom:
+					m.Y = max(m.Y, d)
+				case sideLeft:
+					m.X = min(m.X, -d)
+				case sideRight:
+					m.X = max(m.X, d)
+				}
+			}
+		}
+	}
+	for i, route := range routes {
+		for k := range route {
+			route[k].X += moves[i][k].X
+			route[k].Y += moves[i][k].Y
+		}
+	}
+}
+
+// Sides of a rectangle.
+const (
+	sideTop = iota
+	sideRight
+	sideBottom
+	sideLeft
+)
+
+// alongSide returns the first of obstacles that the segment from p to q
+// runs along a side of, and which side, or -1 if it doesn't run along any.
+func alongSide(obstacles []rect, p, q gg.Point) (obstacle, side int) {
+	const eps = 0.5
+	near := func(a, b float64) bool { return math.Abs(a-b) < eps }
+	within := func(v, lo, hi float64) bool { return v > lo-eps && v < hi+eps }
+	for j, o := range obstacles {
+		switch {
+		case near(p.X, q.X) && within(p.Y, o.minY, o.maxY) && within(q.Y, o.minY, o.maxY):
+			if near(p.X, o.minX) {
+				return j, sideLeft
+			}
+			if near(p.X, o.maxX) {
+				return j, sideRight
+			}
+		case near(p.Y, q.Y) && within(p.X, o.minX, o.maxX) && within(q.X, o.minX, o.maxX):
+			if near(p.Y, o.minY) {
+				return j, sideTop
+			}
+			if near(p.Y, o.maxY) {
+				return j, sideBottom
+			}
+		}
+	}
+	return -1, 0
+}
+
+// visGraph is the visibility graph of the corners of a set of obstacles:
+// which corners can be joined by a straight line that doesn't pass through
+// any of them.
+type visGraph struct {
+	shrunk  []rect // The obstacles, shrunk so paths can run along their edges.
+	nodes   []gg.Point
+	visible [][]bool
+}
+
+func newVisGraph(obstacles []rect) *visGraph {
+	const eps = 0.5
+	g := &visGraph{shrunk: make([]rect, len(obstacles))}
+	for i, o := range obstacles {
+		g.shrunk[i] = o.inset(eps)
+	}
+corners:
+	for _, o := range obstacles {
+		for _, p := range o.corners() {
+			for _, s := range g.shrunk {
+				if s.contains(p) {
+					continue corners
+				}
+			}
+			g.nodes = append(g.nodes, p)
+		}
+	}
+	g.visible = make([][]bool, len(g.nodes))
+	for u := range g.nodes {
+		g.visible[u] = make([]bool, len(g.nodes))
+		for v := range u {
+			vis := g.sees(g.nodes[u], g.nodes[v])
+			g.visible[u][v], g.visible[v][u] = vis, vis
+		}
+	}
+	return g
+}
+
+// sees reports whether the segment from p to q misses all the obstacles.
+func (g *visGraph) sees(p, q gg.Point) bool {
+	for _, o := range g.shrunk {
+		if o.crosses(p, q) {
+			return false
+		}
+	}
+	return true
+}
+
+// shortestPath returns the shortest path from a to b, not including a,
+// that doesn't pass through any of the obstacles. If there's no such path,
+// it returns a straight line.
+func (g *visGraph) shortestPath(a, b gg.Point) []gg.Point {
+	if g.sees(a, b) {
+		return []gg.Point{b}
+	}
+
+	// Dijkstra's algorithm over the graph's nodes, then a and b. The
+	// graph is small, so just scan for the closest unvisited node each
+	// time.
+	n := len(g.nodes)
+	nodes := append(slices.Clip(g.nodes), a, b)
+	src, dst := n, n+1
+	fromA := make([]bool, n)
+	toB := make([]bool, n)
+	for i, p := range g.nodes {
+		fromA[i], toB[i] = g.sees(a, p), g.sees(p, b)
+	}
+	edge := func(u, v int) bool {
+		switch {
+		case u == src:
+			return v < n && fromA[v]
+		case v == dst:
+			return u < n && toB[u]
+		case u < n && v < n:
+			return g.visible[u][v]
+		}
+		return false
+	}
+
+	dist := make([]float64, len(nodes))
+	prev := make([]int, len(nodes))
+	done := make([]bool, len(nodes))
+	for i := range dist {
+		dist[i] = math.Inf(1)
+		prev[i] = -1
+	}
+	dist[src] = 0
+	for {
+		u := -1
+		for i := range nodes {
+			if !done[i] && !math.IsInf(dist[i], 1) && (u < 0 || dist[i] < dist[u]) {
+				u = i
+			}
+		}
+		if u < 0 {
+			return []gg.Point{b}
+		}
+		if u == dst {
+			break
+		}
+		done[u] = true
+		for v := range nodes {
+			if done[v] || !edge(u, v) {
+				continue
+			}
+			if d := dist[u] + math.Hypot(nodes[v].X-nodes[u].X, nodes[v].Y-nodes[u].Y); d < dist[v] {
+				dist[v] = d
+				prev[v] = u
+			}
+		}
+	}
+	var path []gg.Point
+	for v := dst; v != src; v = prev[v] {
+		path = append(path, nodes[v])
+	}
+	slices.Reverse(path)
+	return path
+}
+
+// dedupPoints removes consecutive points in path that are at the same
+// place.
+func dedupPoints(path []gg.Point) []gg.Point {
+	return slices.CompactFunc(path, func(p, q gg.Point) bool {
+		return math.Abs(p.X-q.X) < 0.5 && math.Abs(p.Y-q.Y) < 0.5
+	})
+}
@@ -0,0 +1,664 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Scenario is the file format for a heap and its roots.
+//
+// Unlike Heap, objects are placed directly in the block slot they occupy
+// and pointers name their target by ID, so scenario files can be written by
+// hand (or by the editor) without keeping track of Pointer indices.
+//
+// Roots are plain named variables, or come from goroutine stacks, global
+// segments, and specials, each of which is scanned as its own phase of
+// marking: first the globals, then the specials, then the stacks.
+//
+// A scenario with a script is collected as many times as it takes the
+// mutator to run it, as paced by -gogc, rather than just once.
+//
+// testdata/example.json is a scenario that uses most of the format.
+type Scenario struct {
+	Types    []ScenarioType    `json:"types,omitempty"`
+	Roots    []ScenarioRoot    `json:"roots"`
+	Globals  []ScenarioSegment `json:"globals,omitempty"`
+	Specials []ScenarioSpecial `json:"specials,omitempty"`
+	Stacks   []ScenarioStack   `json:"stacks,omitempty"`
+	Blocks   []ScenarioBlock   `json:"blocks"`
+	Script   []ScenarioStep    `json:"script,omitempty"`
+}
+
+// ScenarioType declares a type that objects can have, in Go syntax, like
+// {"name": "T", "def": "struct{a int; b *T; c [2]int}"}. If a scenario
+// declares types, each object's fields and scalar words are checked
+// against its type, and any left out are filled in.
+type ScenarioType struct {
+	Name string `json:"name"`
+	Def  string `json:"def"`
+}
+
+type ScenarioRoot struct {
+	Name   string `json:"name"`
+	Target string `json:"target,omitempty"` // Like ScenarioField.Target.
+}
+
+// ScenarioSegment is a data or BSS segment holding global variables, one
+// word per slot. Its pointer bitmap is made from which slots hold pointers.
+type ScenarioSegment struct {
+	Section string         `json:"section"` // Like "data" or "bss".
+	Slots   []ScenarioSlot `json:"slots"`
+}
+
+// ScenarioStack is a goroutine's stack, with its frames from the outermost
+// in, like main.main first.
+type ScenarioStack struct {
+	Goroutine int             `json:"goroutine"`
+	Frames    []ScenarioFrame `json:"frames"`
+}
+
+// ScenarioFrame is a stack frame. A frame without a stack map, like one
+// stopped by asynchronous preemption, is scanned conservatively, taking
+// every slot that holds the address of an allocated object for a pointer,
+// including scalars whose values just look like addresses.
+type ScenarioFrame struct {
+	Func         string         `json:"func"`
+	Conservative bool           `json:"conservative,omitempty"`
+	Slots        []ScenarioSlot `json:"slots"`
+}
+
+// ScenarioSlot is a word of a stack frame or global segment. It holds a
+// pointer if it has a target, which is like ScenarioField.Target, or is
+// marked as a pointer; otherwise it holds a scalar, which may be given a
+// value to show.
+type ScenarioSlot struct {
+	Name    string `json:"name"`
+	Pointer bool   `json:"pointer,omitempty"`
+	Target  string `json:"target,omitempty"`
+	Value   string `json:"value,omitempty"`
+}
+
+// ScenarioSpecial is a finalizer or cleanup attached to an object. A
+// finalizer keeps everything its object points to alive, but not the
+// object itself; a cleanup keeps only its function alive. Fn is the ID of
+// the function's closure, if it's in the heap.
+type ScenarioSpecial struct {
+	Kind   string `json:"kind"` // "finalizer" or "cleanup".
+	Object string `json:"object"`
+	Fn     string `json:"fn,omitempty"`
+}
+
+// ScenarioStep is a step of the mutator's script. It either allocates an
+// object, like {"alloc": "x", "type": "T", "size": 16, "fields": [...]},
+// which goes in the first free slot of its size class, or writes a
+// pointer to a target, like ScenarioField.Target, into a root, like
+// {"root": "r", "target": "x"}, or a field, like {"write": "x+8",
+// "target": "y"}. The size of an allocation defaults to the size of its
+// type, if the scenario declares types.
+type ScenarioStep struct {
+	Alloc  string          `json:"alloc,omitempty"` // ID of the new object.
+	Type   string          `json:"type,omitempty"`
+	Size   int             `json:"size,omitempty"`
+	Fields []ScenarioField `json:"fields,omitempty"`
+	Root   string          `json:"root,omitempty"`
+	Write  string          `json:"write,omitempty"`
+	Target string          `json:"target,omitempty"`
+}
+
+// ScenarioBlock is a block of slots. A block may give a size class in
+// place of an element size, making it a span like the runtime's: "span of
+// class 5 at 0xc000000000" is {"address": "0xc000000000", "sizeClass": 5}.
+// Its slots then list the first slots of the span, and the rest are free.
+// A block may instead give a number of pages, making it a span for a
+// single large object, which is its only slot.
+type ScenarioBlock struct {
+	Address   Address           `json:"address"`
+	SizeClass int               `json:"sizeClass,omitempty"`
+	NPages    int               `json:"npages,omitempty"`
+	ElemSize  int               `json:"elemSize,omitempty"` // Implied by a size class or pages.
+	Slots     []*ScenarioObject `json:"slots"`              // nil slots are free.
+}
+
+type ScenarioObject struct {
+	ID      string           `json:"id"`
+	Type    string           `json:"type"`
+	Size    int              `json:"size,omitempty"` // Zero means the whole slot.
+	Fields  []ScenarioField  `json:"fields,omitempty"`
+	Scalars []ScenarioScalar `json:"scalars,omitempty"`
+	Tiny    []ScenarioTiny   `json:"tiny,omitempty"`
+}
+
+// ScenarioTiny is an allocation the tiny allocator packed into an object,
+// which must fill a 16-byte slot and have no words of its own.
+// Allocations are packed in order, and pointers may name one as their
+// target in place of the object holding it.
+type ScenarioTiny struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Size int    `json:"size"`
+}
+
+// ScenarioField is a pointer word of an object. Its target is either an
+// object ID, optionally followed by +offset for a pointer into the middle
+// of the object, like "a+16", or a heap address, like "0xc000010018",
+// which is resolved to the object containing it. Empty means nil. A weak
+// field is a weak pointer, like a weak.Pointer, which isn't traced.
+type ScenarioField struct {
+	Offset int    `json:"offset"`
+	Target string `json:"target,omitempty"`
+	Weak   bool   `json:"weak,omitempty"`
+}
+
+// ScenarioScalar is a non-pointer word of an object, so a layout like
+// struct{a int; b *T; c [2]int} has scalars at offsets 0, 16, and 24 and
+// a field at offset 8.
+type ScenarioScalar struct {
+	Offset int    `json:"offset"`
+	Value  string `json:"value,omitempty"`
+}
+
+// Address is a heap address, encoded in JSON as a hex string.
+type Address uint64
+
+func (a Address) MarshalJSON() ([]byte, error) {
+	return json.Marshal(fmt.Sprintf("%#x", uint64(a)))
+}
+
+func (a *Address) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		// Accept plain numbers too.
+		var n uint64
+		if err := json.Unmarshal(b, &n); err != nil {
+			return fmt.Errorf("address must be a hex string or number: %s", b)
+		}
+		*a = Address(n)
+		return nil
+	}
+	n, err := strconv.ParseUint(strings.TrimPrefix(s, "0x"), 16, 64)
+	if err != nil {
+		return fmt.Errorf("bad address %q: %v", s, err)
+	}
+	*a = Address(n)
+	return nil
+}
+
+// loadScenario reads a scenario file.
+func loadScenario(path string) (*Scenario, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sc := new(Scenario)
+	if err := json.Unmarshal(b, sc); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if _, _, err := sc.Build(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	// Spell out the element sizes of spans for the editor.
+	for i := range sc.Blocks {
+		switch sb := &sc.Blocks[i]; {
+		case sb.SizeClass != 0:
+			sb.ElemSize = classToSize[sb.SizeClass]
+		case sb.NPages != 0:
+			sb.ElemSize = sb.NPages * pageSize
+		}
+	}
+	return sc, nil
+}
+
+// save writes the scenario to a file.
+func (sc *Scenario) save(path string) error {
+	b, err := json.MarshalIndent(sc, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// Build creates a fresh heap and roots from the scenario.
+func (sc *Scenario) Build() ([]Root, *Heap, error) {
+	heap := &Heap{
+		Objects: []Object{
+			Nil:  Obj("nil"),
+			Free: Obj("<free>"),
+		},
+	}
+	for _, st := range sc.Types {
+		heap.Types = append(heap.Types, TypeDecl{st.Name, st.Def})
+	}
+	ids := make(map[string]Pointer)
+	tiny := make(map[string]int) // Offsets of tiny allocations in ids.
+	for _, sb := range sc.Blocks {
+		var b Block
+		switch {
+		case sb.SizeClass != 0 && sb.NPages != 0:
+			return nil, nil, fmt.Errorf("block %#x: a span has a size class or a number of pages, not both", sb.Address)
+		case sb.NPages != 0:
+			if sb.NPages < 0 {
+				return nil, nil, fmt.Errorf("block %#x: bad number of pages %d", sb.Address, sb.NPages)
+			}
+			b = Block{Address: uint64(sb.Address), ElemSize: sb.NPages * pageSize, NPages: sb.NPages}
+			if sb.ElemSize != 0 && sb.ElemSize != b.ElemSize {
+				return nil, nil, fmt.Errorf("block %#x: element size %d doesn't match %d pages", sb.Address, sb.ElemSize, sb.NPages)
+			}
+			if len(sb.Slots) != 1 {
+				return nil, nil, fmt.Errorf("block %#x: a large-object span has one slot, not %d", sb.Address, len(sb.Slots))
+			}
+		case sb.SizeClass != 0:
+			if sb.SizeClass < 0 || sb.SizeClass >= numSizeClasses {
+				return nil, nil, fmt.Errorf("block %#x: no size class %d", sb.Address, sb.SizeClass)
+			}
+			b = Span(uint64(sb.Address), sb.SizeClass)
+			if sb.ElemSize != 0 && sb.ElemSize != b.ElemSize {
+				return nil, nil, fmt.Errorf("block %#x: element size %d doesn't match size class %d, which is %d", sb.Address, sb.ElemSize, sb.SizeClass, b.ElemSize)
+			}
+			if len(sb.Slots) > b.NElems() {
+				return nil, nil, fmt.Errorf("block %#x: %d slots, but a span of class %d has only %d", sb.Address, len(sb.Slots), sb.SizeClass, b.NElems())
+			}
+		default:
+			if sb.ElemSize <= 0 || sb.ElemSize%PointerSize != 0 {
+				return nil, nil, fmt.Errorf("block %#x: element size %d is not a positive multiple of %d", sb.Address, sb.ElemSize, PointerSize)
+			}
+			b = Blk(uint64(sb.Address), sb.ElemSize)
+		}
+		if b.NPages != 0 && sb.Address%pageSize != 0 {
+			return nil, nil, fmt.Errorf("block %#x: span is not aligned to a %d-byte page", sb.Address, pageSize)
+		}
+		for _, so := range sb.Slots {
+			if so == nil {
+				b.Objects = append(b.Objects, Free)
+				continue
+			}
+			if so.ID == "" {
+				return nil, nil, fmt.Errorf("block %#x: object of type %s has no ID", sb.Address, so.Type)
+			}
+			if _, ok := ids[so.ID]; ok {
+				return nil, nil, fmt.Errorf("duplicate object ID %q", so.ID)
+			}
+			if so.Size < 0 || so.Size%PointerSize != 0 || so.Size > b.ElemSize {
+				return nil, nil, fmt.Errorf("object %q: size %d is not a multiple of %d that fits in a %d-byte slot", so.ID, so.Size, PointerSize, b.ElemSize)
+			}
+			p := Pointer(len(heap.Objects))
+			ids[so.ID] = p
+			obj := Obj(so.Type)
+			obj.Size = so.Size
+			if len(so.Tiny) != 0 {
+				if b.ElemSize != maxTinySize || so.Size != 0 || len(so.Fields) != 0 || len(so.Scalars) != 0 {
+					return nil, nil, fmt.Errorf("object %q: tiny allocations must fill a %d-byte slot with no other words", so.ID, maxTinySize)
+				}
+				if obj.Type == "" {
+					obj.Type = tinyType
+				}
+				for _, st := range so.Tiny {
+					if st.ID == "" {
+						return nil, nil, fmt.Errorf("object %q: tiny allocation of type %s has no ID", so.ID, st.Type)
+					}
+					if _, ok := ids[st.ID]; ok {
+						return nil, nil, fmt.Errorf("duplicate object ID %q", st.ID)
+					}
+					if err := obj.packTiny(st.Type, st.Size); err != nil {
+						return nil, nil, fmt.Errorf("object %q: %v", so.ID, err)
+					}
+					// The collector only sees the object holding
+					// the allocation.
+					ids[st.ID] = p
+					tiny[st.ID] = obj.Tiny[len(obj.Tiny)-1].Offset
+				}
+			}
+			heap.Objects = append(heap.Objects, obj)
+			b.Objects = append(b.Objects, p)
+		}
+		heap.Blocks = append(heap.Blocks, b)
+	}
+
+	res := &resolver{heap: heap, ids: ids, tiny: tiny, sizes: make(map[Pointer]int)}
+	var errs []error
+	for i, sb := range sc.Blocks {
+		for _, so := range sb.Slots {
+			if so == nil {
+				continue
+			}
+			obj := &heap.Objects[ids[so.ID]]
+			size := so.Size
+			if size == 0 {
+				size = heap.Blocks[i].ElemSize
+			}
+			used := make(map[int]bool)
+			checkOffset := func(what string, off int) bool {
+				if off < 0 || off%PointerSize != 0 || off >= size {
+					errs = append(errs, fmt.Errorf("object %q: %s offset %d is not a pointer-aligned offset in a %d-byte object", so.ID, what, off, size))
+					return false
+				}
+				if used[off] {
+					errs = append(errs, fmt.Errorf("object %q: more than one word at offset %d", so.ID, off))
+					return false
+				}
+				used[off] = true
+				return true
+			}
+			for _, sf := range so.Fields {
+				if !checkOffset("field", sf.Offset) {
+					continue
+				}
+				p, off, err := res.resolve(sf.Target)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("object %q: field %d: %v", so.ID, sf.Offset, err))
+				}
+				f := F(sf.Offset, p)
+				f.Interior, f.Weak = off, sf.Weak
+				obj.Fields = append(obj.Fields, f)
+			}
+			for _, ss := range so.Scalars {
+				if checkOffset("scalar", ss.Offset) {
+					obj.Scalars = append(obj.Scalars, S(ss.Offset, ss.Value))
+				}
+			}
+		}
+	}
+	var roots []Root
+	for _, sr := range sc.Roots {
+		p, off, err := res.resolve(sr.Target)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("root %q: %v", sr.Name, err))
+		}
+		roots = append(roots, Root{Name: sr.Name, Pointer: p, Interior: off})
+	}
+	slots := func(base Root, slots []ScenarioSlot) {
+		for i, ss := range slots {
+			r := base
+			r.Name, r.Offset = ss.Name, i*PointerSize
+			if ss.Target == "" && !ss.Pointer {
+				r.Scalar, r.Value = true, ss.Value
+				roots = append(roots, r)
+				continue
+			}
+			p, off, err := res.resolve(ss.Target)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: slot %s: %v", base.Set, ss.Name, err))
+			}
+			r.Pointer, r.Interior = p, off
+			roots = append(roots, r)
+		}
+	}
+	for _, seg := range sc.Globals {
+		if seg.Section == "" {
+			errs = append(errs, errors.New("global segment has no section name"))
+		}
+		slots(Root{Kind: GlobalRoot, Set: seg.Section}, seg.Slots)
+	}
+	for _, sp := range sc.Specials {
+		obj, _, err := res.resolve(sp.Object)
+		if err == nil && obj == Nil {
+			err = errors.New("no object")
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s on %q: %v", sp.Kind, sp.Object, err))
+			continue
+		}
+		fn, _, err := res.resolve(sp.Fn)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s on %q: func: %v", sp.Kind, sp.Object, err))
+		}
+		var name string
+		switch sp.Kind {
+		case "finalizer":
+			roots = append(roots, Root{Name: "finalizer on " + sp.Object, Pointer: obj, Kind: FinalizerRoot, Set: "specials", Special: sp.Kind, Object: obj})
+			name = "finalizer func"
+		case "cleanup":
+			name = "cleanup on " + sp.Object
+		default:
+			errs = append(errs, fmt.Errorf("special on %q: unknown kind %q, want finalizer or cleanup", sp.Object, sp.Kind))
+			continue
+		}
+		roots = append(roots, Root{Name: name, Pointer: fn, Kind: SpecialRoot, Set: "specials", Special: sp.Kind, Object: obj})
+	}
+	for _, st := range sc.Stacks {
+		set := fmt.Sprintf("goroutine %d", st.Goroutine)
+		for i, f := range st.Frames {
+			slots(Root{Kind: StackRoot, Set: set, Frame: i, Func: f.Func, Conservative: f.Conservative}, f.Slots)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, nil, err
+	}
+	if err := heap.applyTypes(); err != nil {
+		return nil, nil, err
+	}
+	script, err := sc.buildScript(heap, roots, res)
+	if err != nil {
+		return nil, nil, err
+	}
+	heap.Script = script
+	heap.initAllocBits()
+	return roots, heap, nil
+}
+
+// resolver resolves the targets of pointers in a scenario, which are either
+// the ID of an object, with an optional "+offset" into it, or an address in
+// hex, to the object and the offset into it.
+type resolver struct {
+	heap *Heap
+	ids  map[string]Pointer
+	tiny map[string]int // Offsets of tiny allocations in ids.
+
+	// sizes holds the sizes of objects the script allocates, which
+	// aren't in heap yet.
+	sizes map[Pointer]int
+}
+
+func (r *resolver) resolve(target string) (Pointer, int, error) {
+	if target == "" {
+		return Nil, 0, nil
+	}
+	var p Pointer
+	var off int
+	if strings.HasPrefix(target, "0x") {
+		addr, err := strconv.ParseUint(target[2:], 16, 64)
+		if err != nil {
+			return Nil, 0, fmt.Errorf("bad address %q", target)
+		}
+		switch p, off = r.heap.FindObject(addr); p {
+		case Nil:
+			return Nil, 0, fmt.Errorf("%s is not in any block", target)
+		case Free:
+			return Nil, 0, fmt.Errorf("%s is in a free slot", target)
+		}
+	} else {
+		id, plus, ok := strings.Cut(target, "+")
+		if ok {
+			n, err := strconv.Atoi(plus)
+			if err != nil || n < 0 {
+				return Nil, 0, fmt.Errorf("bad offset in %q", target)
+			}
+			off = n
+		}
+		if p, ok = r.ids[id]; !ok {
+			return Nil, 0, fmt.Errorf("no object with ID %q", id)
+		}
+		off += r.tiny[id]
+	}
+	size, ok := r.sizes[p]
+	if !ok {
+		size = r.heap.SizeOf(p)
+	}
+	if off >= size {
+		return Nil, 0, fmt.Errorf("%s is past the end of a %d-byte object", target, size)
+	}
+	return p, off, nil
+}
+
+// buildScript resolves the scenario's script against heap and its roots
+// with r, to which it adds the objects the script allocates.
+func (sc *Scenario) buildScript(heap *Heap, roots []Root, r *resolver) ([]Step, error) {
+	var env *typeEnv
+	if len(heap.Types) != 0 {
+		var err error
+		if env, err = newTypeEnv(heap.Types); err != nil {
+			return nil, err
+		}
+	}
+	// Pointer fields of the objects the script allocates, which are
+	// numbered on from the heap's.
+	allocated := make(map[Pointer][]Field)
+	fieldsOf := func(p Pointer) []Field {
+		if int(p) < len(heap.Objects) {
+			return heap.Objects[p].Fields
+		}
+		return allocated[p]
+	}
+
+	var steps []Step
+	var errs []error
+	for i, ss := range sc.Script {
+		fail := func(err error) {
+			errs = append(errs, fmt.Errorf("script step %d: %v", i, err))
+		}
+		st := Step{Root: -1}
+		switch {
+		case ss.Alloc != "":
+			if _, ok := r.ids[ss.Alloc]; ok {
+				fail(fmt.Errorf("duplicate object ID %q", ss.Alloc))
+				continue
+			}
+			obj := Obj(ss.Type)
+			size := ss.Size
+			var l *typeLayout
+			if env != nil {
+				var err error
+				if l, err = env.layout(ss.Type); err != nil {
+					fail(err)
+					continue
+				}
+				if size == 0 {
+					size = l.size
+				}
+			}
+			if size <= 0 {
+				fail(fmt.Errorf("object %q: no size", ss.Alloc))
+				continue
+			}
+			// The slot it goes in isn't known until the script runs, so
+			// alloc records its size then.
+			b := &Block{ElemSize: slotSize(size)}
+			size = (size + PointerSize - 1) / PointerSize * PointerSize
+			for _, sf := range ss.Fields {
+				if sf.Offset < 0 || sf.Offset%PointerSize != 0 || sf.Offset >= size {
+					fail(fmt.Errorf("object %q: field offset %d is not a pointer-aligned offset in a %d-byte object", ss.Alloc, sf.Offset, size))
+					continue
+				}
+				p, off, err := r.resolve(sf.Target)
+				if err != nil {
+					fail(fmt.Errorf("object %q: field %d: %v", ss.Alloc, sf.Offset, err))
+				}
+				f := F(sf.Offset, p)
+				f.Interior, f.Weak = off, sf.Weak
+				obj.Fields = append(obj.Fields, f)
+			}
+			if l != nil {
+				if err := obj.applyLayout(l, b); err != nil {
+					fail(fmt.Errorf("object %q: %v", ss.Alloc, err))
+				}
+			}
+			p := Pointer(len(heap.Objects) + len(allocated))
+			r.ids[ss.Alloc] = p
+			r.sizes[p] = size
+			allocated[p] = obj.Fields
+			st.Alloc, st.Size = &obj, size
+		case ss.Root != "" || ss.Write != "":
+			p, off, err := r.resolve(ss.Target)
+			if err != nil {
+				fail(err)
+				continue
+			}
+			st.Target, st.Interior = p, off
+			if ss.Root != "" {
+				st.Root = slices.IndexFunc(roots, func(r Root) bool { return r.Name == ss.Root && !r.Scalar })
+				if st.Root < 0 {
+					fail(fmt.Errorf("no root %q", ss.Root))
+					continue
+				}
+				break
+			}
+			if st.Object, st.Offset, err = r.resolve(ss.Write); err != nil {
+				fail(err)
+				continue
+			}
+			if !slices.ContainsFunc(fieldsOf(st.Object), func(f Field) bool { return f.Offset == st.Offset }) {
+				fail(fmt.Errorf("%s doesn't hold a pointer", ss.Write))
+				continue
+			}
+		default:
+			fail(errors.New("want alloc, root, or write"))
+			continue
+		}
+		steps = append(steps, st)
+	}
+	return steps, errors.Join(errs...)
+}
+
+// scenarioFromHeap converts a heap and its roots, which must be plain
+// variables, into a Scenario.
+// Objects are given IDs derived from their addresses.
+func scenarioFromHeap(roots []Root, heap *Heap) *Scenario {
+	id := func(p Pointer) string {
+		if p == Nil || p == Free {
+			return ""
+		}
+		return fmt.Sprintf("%x", heap.AddressOf(p))
+	}
+	target := func(p Pointer, off int) string {
+		if off == 0 {
+			return id(p)
+		}
+		for i, t := range heap.Objects[p].Tiny {
+			if t.Offset == off {
+				return fmt.Sprintf("%s.%d", id(p), i)
+			}
+		}
+		return fmt.Sprintf("%s+%d", id(p), off)
+	}
+	sc := new(Scenario)
+	for _, t := range heap.Types {
+		sc.Types = append(sc.Types, ScenarioType{t.Name, t.Def})
+	}
+	for _, r := range roots {
+		sc.Roots = append(sc.Roots, ScenarioRoot{r.Name, target(r.Pointer, r.Interior)})
+	}
+	for _, b := range heap.Blocks {
+		sb := ScenarioBlock{Address: Address(b.Address), SizeClass: b.SizeClass, ElemSize: b.ElemSize, Slots: []*ScenarioObject{}}
+		if b.Large() {
+			sb.NPages = b.NPages
+		}
+		for _, p := range b.Objects {
+			if p == Free {
+				sb.Slots = append(sb.Slots, nil)
+				continue
+			}
+			obj := &heap.Objects[p]
+			so := &ScenarioObject{ID: id(p), Type: obj.Type, Size: obj.Size}
+			for _, f := range obj.Fields {
+				so.Fields = append(so.Fields, ScenarioField{f.Offset, target(f.Pointer, f.Interior), f.Weak})
+			}
+			for _, sc := range obj.Scalars {
+				so.Scalars = append(so.Scalars, ScenarioScalar{sc.Offset, sc.Value})
+			}
+			for i, t := range obj.Tiny {
+				so.Tiny = append(so.Tiny, ScenarioTiny{fmt.Sprintf("%s.%d", so.ID, i), t.Type, t.Size})
+			}
+			sb.Slots = append(sb.Slots, so)
+		}
+		sc.Blocks = append(sc.Blocks, sb)
+	}
+	return sc
+}
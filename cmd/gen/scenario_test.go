@@ -0,0 +1,233 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func parseScenario(t *testing.T, src string) *Scenario {
+	t.Helper()
+	sc := new(Scenario)
+	if err := json.Unmarshal([]byte(src), sc); err != nil {
+		t.Fatalf("%s: %v", src, err)
+	}
+	return sc
+}
+
+func TestBuildErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"duplicate ID",
+			`{"blocks": [{"address": "0x1000", "elemSize": 16, "slots": [{"id": "a"}, {"id": "a"}]}]}`,
+			`duplicate object ID "a"`},
+		{"no ID",
+			`{"blocks": [{"address": "0x1000", "elemSize": 16, "slots": [{"type": "T"}]}]}`,
+			"has no ID"},
+		{"bad element size",
+			`{"blocks": [{"address": "0x1000", "elemSize": 12, "slots": []}]}`,
+			"not a positive multiple of 8"},
+		{"class and pages",
+			`{"blocks": [{"address": "0x2000", "sizeClass": 2, "npages": 1, "slots": []}]}`,
+			"not both"},
+		{"no such class",
+			`{"blocks": [{"address": "0x2000", "sizeClass": 999, "slots": []}]}`,
+			"no size class 999"},
+		{"class size mismatch",
+			`{"blocks": [{"address": "0x2000", "sizeClass": 2, "elemSize": 32, "slots": []}]}`,
+			"doesn't match size class 2"},
+		{"too many slots",
+			`{"blocks": [{"address": "0x2000", "sizeClass": 40, "slots": [null, null, null, null]}]}`,
+			"4 slots, but a span of class 40 has only 3"},
+		{"unaligned span",
+			`{"blocks": [{"address": "0x2008", "sizeClass": 2, "slots": []}]}`,
+			"not aligned"},
+		{"large span with two slots",
+			`{"blocks": [{"address": "0x2000", "npages": 1, "slots": [null, null]}]}`,
+			"one slot, not 2"},
+		{"object too big",
+			`{"blocks": [{"address": "0x1000", "elemSize": 16, "slots": [{"id": "a", "size": 24}]}]}`,
+			"fits in a 16-byte slot"},
+		{"misaligned field",
+			`{"blocks": [{"address": "0x1000", "elemSize": 16, "slots": [{"id": "a", "fields": [{"offset": 4}]}]}]}`,
+			"field offset 4 is not a pointer-aligned offset"},
+		{"field past the end",
+			`{"blocks": [{"address": "0x1000", "elemSize": 16, "slots": [{"id": "a", "fields": [{"offset": 16}]}]}]}`,
+			"field offset 16"},
+		{"two words at one offset",
+			`{"blocks": [{"address": "0x1000", "elemSize": 16, "slots": [{"id": "a", "fields": [{"offset": 0}], "scalars": [{"offset": 0}]}]}]}`,
+			"more than one word at offset 0"},
+		{"unknown target",
+			`{"roots": [{"name": "x", "target": "b"}], "blocks": [{"address": "0x1000", "elemSize": 16, "slots": [{"id": "a"}]}]}`,
+			`no object with ID "b"`},
+		{"target past the end",
+			`{"roots": [{"name": "x", "target": "a+16"}], "blocks": [{"address": "0x1000", "elemSize": 16, "slots": [{"id": "a"}]}]}`,
+			"past the end of a 16-byte object"},
+		{"bad offset",
+			`{"roots": [{"name": "x", "target": "a+x"}], "blocks": [{"address": "0x1000", "elemSize": 16, "slots": [{"id": "a"}]}]}`,
+			"bad offset"},
+		{"address outside the heap",
+			`{"roots": [{"name": "x", "target": "0x5000"}], "blocks": [{"address": "0x1000", "elemSize": 16, "slots": [{"id": "a"}]}]}`,
+			"not in any block"},
+		{"address in a free slot",
+			`{"roots": [{"name": "x", "target": "0x1010"}], "blocks": [{"address": "0x1000", "elemSize": 16, "slots": [{"id": "a"}, null]}]}`,
+			"in a free slot"},
+		{"tiny in the wrong block",
+			`{"blocks": [{"address": "0x1000", "elemSize": 32, "slots": [{"id": "t", "tiny": [{"id": "x", "type": "byte", "size": 1}]}]}]}`,
+			"tiny allocations must fill a 16-byte slot"},
+		{"tiny overflow",
+			`{"blocks": [{"address": "0x1000", "elemSize": 16, "slots": [{"id": "t", "tiny": [{"id": "x", "type": "int64", "size": 8}, {"id": "y", "type": "int32", "size": 4}, {"id": "z", "type": "int64", "size": 8}]}]}]}`,
+			"don't fit"},
+		{"type mismatch",
+			`{"types": [{"name": "T", "def": "struct{p *T; n int}"}], "blocks": [{"address": "0x1000", "elemSize": 16, "slots": [{"id": "a", "type": "T", "fields": [{"offset": 8}]}]}]}`,
+			"object T at 0x1000"},
+		{"script alloc duplicate",
+			`{"roots": [{"name": "x"}], "blocks": [{"address": "0x1000", "elemSize": 16, "slots": [{"id": "a"}]}], "script": [{"alloc": "a", "type": "T", "size": 16}]}`,
+			`script step 0: duplicate object ID "a"`},
+		{"script alloc without size",
+			`{"blocks": [{"address": "0x1000", "elemSize": 16, "slots": [{"id": "a"}]}], "script": [{"alloc": "b", "type": "T"}]}`,
+			`object "b": no size`},
+		{"script unknown root",
+			`{"roots": [{"name": "x"}], "blocks": [{"address": "0x1000", "elemSize": 16, "slots": [{"id": "a"}]}], "script": [{"root": "y", "target": "a"}]}`,
+			`no root "y"`},
+		{"script write to a scalar",
+			`{"blocks": [{"address": "0x1000", "elemSize": 16, "slots": [{"id": "a", "fields": [{"offset": 0}], "scalars": [{"offset": 8}]}]}], "script": [{"write": "a+8", "target": "a"}]}`,
+			"a+8 doesn't hold a pointer"},
+		{"script target past the end",
+			`{"roots": [{"name": "x"}], "blocks": [{"address": "0x1000", "elemSize": 16, "slots": [{"id": "a"}]}], "script": [{"alloc": "b", "type": "T", "size": 8}, {"root": "x", "target": "b+8"}]}`,
+			"script step 1: b+8 is past the end of a 8-byte object"},
+		{"script empty step",
+			`{"blocks": [{"address": "0x1000", "elemSize": 16, "slots": [{"id": "a"}]}], "script": [{}]}`,
+			"want alloc, root, or write"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := parseScenario(t, tt.src).Build()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Build error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildScriptTargets(t *testing.T) {
+	// Script targets resolve like the heap's: by ID, with an offset, or
+	// by address.
+	sc := parseScenario(t, `{
+		"roots": [{"name": "x"}],
+		"blocks": [{"address": "0x1000", "elemSize": 16, "slots": [
+			{"id": "a", "fields": [{"offset": 0}, {"offset": 8}]},
+			{"id": "b", "fields": [{"offset": 0}, {"offset": 8}]}
+		]}],
+		"script": [
+			{"write": "0x1018", "target": "a+8"},
+			{"root": "x", "target": "0x1004"},
+			{"alloc": "c", "type": "T", "size": 16, "fields": [{"offset": 8, "target": "b+8"}]},
+			{"root": "x", "target": "c+8"}
+		]
+	}`)
+	_, h, err := sc.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b, c := Pointer(2), Pointer(3), Pointer(4)
+	want := []Step{
+		{Root: -1, Object: b, Offset: 8, Target: a, Interior: 8},
+		{Root: 0, Target: a, Interior: 4},
+		{Root: -1, Alloc: h.Script[2].Alloc, Size: 16},
+		{Root: 0, Target: c, Interior: 8},
+	}
+	if !reflect.DeepEqual(h.Script, want) {
+		t.Errorf("Script = %+v, want %+v", h.Script, want)
+	}
+	if f := h.Script[2].Alloc.Fields; len(f) != 1 || f[0].Pointer != b || f[0].Interior != 8 {
+		t.Errorf("allocated object's fields = %+v, want one pointing to b+8", f)
+	}
+}
+
+func TestScenarioRoundTrip(t *testing.T) {
+	built := func(src string) func() ([]Root, *Heap) {
+		return func() ([]Root, *Heap) {
+			roots, h, err := parseScenario(t, src).Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			return roots, h
+		}
+	}
+	tests := []struct {
+		name string
+		heap func() ([]Root, *Heap)
+	}{
+		{"built-in", makeHeap},
+		{"spans", built(`{
+			"roots": [{"name": "x", "target": "a+8"}, {"name": "y", "target": "s"}, {"name": "z"}],
+			"blocks": [
+				{"address": "0xc000000000", "sizeClass": 3, "slots": [
+					{"id": "a", "type": "T", "fields": [{"offset": 0, "target": "big"}, {"offset": 8, "target": "b", "weak": true}], "scalars": [{"offset": 16, "value": "7"}]},
+					null,
+					{"id": "b", "type": "T", "size": 16, "fields": [{"offset": 0, "target": "a"}]}
+				]},
+				{"address": "0xc000002000", "sizeClass": 2, "slots": [
+					{"id": "t", "tiny": [{"id": "r", "type": "int32", "size": 4}, {"id": "s", "type": "[6]byte", "size": 6}]}
+				]},
+				{"address": "0xc000004000", "npages": 1, "slots": [
+					{"id": "big", "type": "[1000]*T", "fields": [{"offset": 7992, "target": "s"}]}
+				]}
+			]
+		}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := scenarioFromHeap(tt.heap())
+
+			// Go through JSON, like the editor does.
+			data, err := json.Marshal(want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			roots, h, err := parseScenario(t, string(data)).Build()
+			if err != nil {
+				t.Fatalf("Build: %v\n%s", err, data)
+			}
+			if got := scenarioFromHeap(roots, h); !reflect.DeepEqual(got, want) {
+				gotData, _ := json.Marshal(got)
+				t.Errorf("round trip changed the scenario:\ngot  %s\nwant %s", gotData, data)
+			}
+		})
+	}
+}
+
+func TestExampleScenario(t *testing.T) {
+	sc, err := loadScenario("testdata/example.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs, err := recordAll(func() ([]Root, *Heap) {
+		roots, h, err := sc.Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return roots, h
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range runs {
+		if len(r.Frames) == 0 {
+			t.Errorf("%s: no frames", r.Name)
+		}
+		last := r.Frames[len(r.Frames)-1].State.Heap()
+		if last.Steps != len(last.Script) {
+			t.Errorf("%s: ran %d of %d script steps", r.Name, last.Steps, len(last.Script))
+		}
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+//go:embed viewer.html
+var viewerHTML []byte
+
+// serveMain implements the "serve" subcommand, which hosts a local viewer
+// for stepping through every run.
+func serveMain(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	fs.Func("format", "frame format: png or svg (default png)", func(v string) error {
+		if v != "png" && v != "svg" {
+			return fmt.Errorf("bad format %q: want png or svg", v)
+		}
+		formatFlag = v
+		return nil
+	})
+	registerCommonFlags(fs)
+	fs.Parse(args)
+
+	runs, err := recordAll(heapSource())
+	must(err)
+	srv := newServer(runs)
+	log.Printf("serving on http://%s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, srv))
+}
+
+type server struct {
+	mux  *http.ServeMux
+	runs []*Run
+
+	// renderMu serializes rendering, since the font caches and the
+	// font faces themselves are not safe for concurrent use.
+	renderMu sync.Mutex
+}
+
+func newServer(runs []*Run) *server {
+	s := &server{mux: http.NewServeMux(), runs: runs}
+	s.mux.HandleFunc("GET /{$}", s.handleIndex)
+	s.mux.HandleFunc("GET /runs", s.handleRuns)
+	s.mux.HandleFunc("GET /frame/{run}/{step}", s.handleFrame)
+	s.mux.HandleFunc("GET /subtitles/{run}", s.handleSubtitles)
+	return s
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(viewerHTML)
+}
+
+// runInfo is the JSON description of a Run sent to the viewer.
+type runInfo struct {
+	Name     string   `json:"name"`
+	Captions []string `json:"captions"`
+	Holds    []int64  `json:"holds"` // In milliseconds.
+}
+
+func (s *server) handleRuns(w http.ResponseWriter, r *http.Request) {
+	var resp struct {
+		Format string    `json:"format"`
+		Runs   []runInfo `json:"runs"`
+	}
+	resp.Format = formatFlag
+	for _, run := range s.runs {
+		info := runInfo{Name: run.Name}
+		for _, f := range run.Frames {
+			info.Captions = append(info.Captions, f.Caption)
+			info.Holds = append(info.Holds, f.Hold.Milliseconds())
+		}
+		resp.Runs = append(resp.Runs, info)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Print(err)
+	}
+}
+
+// run returns the run named by the request's "run" path value, or nil.
+func (s *server) run(r *http.Request) *Run {
+	for _, run := range s.runs {
+		if run.Name == r.PathValue("run") {
+			return run
+		}
+	}
+	return nil
+}
+
+func (s *server) handleFrame(w http.ResponseWriter, r *http.Request) {
+	run := s.run(r)
+	if run == nil {
+		http.NotFound(w, r)
+		return
+	}
+	step, err := strconv.Atoi(r.PathValue("step"))
+	if err != nil || step < 0 || step >= len(run.Frames) {
+		http.NotFound(w, r)
+		return
+	}
+	format := r.FormValue("format")
+	if format == "" {
+		format = formatFlag
+	}
+
+	var buf bytes.Buffer
+	s.renderMu.Lock()
+	err = encodeFrame(&buf, format, run.Frames[step])
+	s.renderMu.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	switch format {
+	case "png":
+		w.Header().Set("Content-Type", "image/png")
+	case "svg":
+		w.Header().Set("Content-Type", "image/svg+xml")
+	}
+	w.Write(buf.Bytes())
+}
+
+func (s *server) handleSubtitles(w http.ResponseWriter, r *http.Request) {
+	run := s.run(r)
+	if run == nil {
+		http.NotFound(w, r)
+		return
+	}
+	var err error
+	switch format := r.FormValue("format"); format {
+	case "", "vtt":
+		w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
+		err = writeVTT(w, run)
+	case "srt":
+		w.Header().Set("Content-Type", "application/x-subrip; charset=utf-8")
+		w.Header().Set("Content-Disposition", "attachment; filename="+run.Name+".srt")
+		err = writeSRT(w, run)
+	default:
+		http.Error(w, "unknown subtitle format "+format, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Print(err)
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+// snapshot is an immutable copy of a gcState at one step of a run.
+//
+// Collectors mutate their state in place as they yield, so anything that
+// wants to render a step after the fact (for example, the HTTP viewer) must
+// take a snapshot first.
+type snapshot struct {
+	roots         []Root
+	rootsVisited  int
+	heap          *Heap
+	marked        Set[Pointer]
+	queued        Set[Pointer]
+	blockQueued   Set[int]
+	fieldsVisited map[Pointer]int
+	workLists     []WorkList
+	ctx           Context
+}
+
+// scannedSnapshot is a snapshot of a gcState that also implements
+// gcStateScanned.
+type scannedSnapshot struct {
+	*snapshot
+	scanned Set[Pointer]
+}
+
+func takeSnapshot(s gcState) gcState {
+	roots, rootsVisited := s.Roots()
+	h := s.Heap()
+	snap := &snapshot{
+		roots:         append([]Root(nil), roots...),
+		rootsVisited:  rootsVisited,
+		heap:          h.Clone(),
+		fieldsVisited: make(map[Pointer]int),
+		ctx:           s.Context(),
+	}
+	for i := range h.Objects {
+		p := Pointer(i)
+		if s.Marked(p) {
+			snap.marked.Add(p)
+		}
+		if s.Queued(p) {
+			snap.queued.Add(p)
+		}
+		if n := s.FieldsVisited(p); n != 0 {
+			snap.fieldsVisited[p] = n
+		}
+	}
+	for i := range h.Blocks {
+		b := &h.Blocks[i]
+		if s.BlockQueued(b) {
+			snap.blockQueued.Add(i)
+		}
+		if snap.ctx.Block == b {
+			snap.ctx.Block = &snap.heap.Blocks[i]
+		}
+	}
+	if wl, ok := s.(gcStateWorkList); ok {
+		for _, list := range wl.WorkLists() {
+			items := make([]WorkItem, 0, len(list.Items))
+			for _, it := range list.Items {
+				if it.Block != nil {
+					it.Block = &snap.heap.Blocks[blockIndex(h, it.Block)]
+				}
+				items = append(items, it)
+			}
+			list.Items = items
+			snap.workLists = append(snap.workLists, list)
+		}
+	}
+	ss, ok := s.(gcStateScanned)
+	if !ok {
+		return snap
+	}
+	scanned := &scannedSnapshot{snapshot: snap}
+	for i := range h.Objects {
+		if ss.Scanned(Pointer(i)) {
+			scanned.scanned.Add(Pointer(i))
+		}
+	}
+	return scanned
+}
+
+func (s *snapshot) Heap() *Heap {
+	return s.heap
+}
+
+func (s *snapshot) Roots() ([]Root, int) {
+	return s.roots, s.rootsVisited
+}
+
+func (s *snapshot) Marked(p Pointer) bool {
+	return s.marked.Has(p)
+}
+
+func (s *snapshot) FieldsVisited(p Pointer) int {
+	return s.fieldsVisited[p]
+}
+
+func (s *snapshot) Queued(p Pointer) bool {
+	return s.queued.Has(p)
+}
+
+func (s *snapshot) BlockQueued(b *Block) bool {
+	for i := range s.heap.Blocks {
+		if &s.heap.Blocks[i] == b {
+			return s.blockQueued.Has(i)
+		}
+	}
+	return false
+}
+
+func (s *snapshot) WorkLists() []WorkList {
+	return s.workLists
+}
+
+func (s *snapshot) Context() Context {
+	return s.ctx
+}
+
+func (s *scannedSnapshot) Scanned(p Pointer) bool {
+	return s.scanned.Has(p)
+}
@@ -0,0 +1,135 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+// Weak pointers, finalizers, and cleanups.
+//
+// None of these keep the object they're for alive, so they can only be
+// dealt with once marking has found everything that is. Weak pointers to
+// objects that weren't marked are cleared, so that they can't bring them
+// back. Then each finalizer and cleanup on such an object is queued to
+// run, which removes it from the object. An object with a finalizer must
+// survive for the finalizer to use it, so it's resurrected: marked after
+// all, to be freed by the next cycle if nothing else has picked it up by
+// then. What it points to strongly is already marked, since the
+// finalizer's root kept it alive, but its weak pointers are cleared like
+// any others to objects that weren't marked. Cleanups don't get to see
+// their object, so it can be freed right away.
+//
+// The runtime does this as it sweeps each span; here it's a phase of its
+// own, after marking, so that it can be shown before anything is freed.
+
+// Phases of finishMark.
+const (
+	weakPhase    = "clear weak pointers to unmarked objects"
+	specialPhase = "queue finalizers and cleanups of unmarked objects"
+)
+
+// finishMark clears weak pointers and queues specials as described above,
+// yielding s after each, and reports whether to keep going. It calls mark
+// to resurrect an object, and skips each phase that has nothing to look
+// at. The caller must deactivate ctx beforehand.
+func finishMark(s gcState, ctx *Context, mark func(Pointer), yield func(gcState) bool) bool {
+	roots, _ := s.Roots()
+	h := s.Heap()
+
+	// Which objects died, before any are resurrected, and which of them
+	// will be for their finalizers.
+	var dead, finalized Set[Pointer]
+	for _, r := range roots {
+		if r.Special == "" || r.Queued || s.Marked(r.Object) {
+			continue
+		}
+		dead.Add(r.Object)
+		if r.Special == "finalizer" {
+			finalized.Add(r.Object)
+		}
+	}
+
+	if hasWeak(h) {
+		ctx.Phase = weakPhase
+
+		// Yield new phase state.
+		if !yield(s) {
+			return false
+		}
+		for i := range h.Objects {
+			p := Pointer(i)
+			if !s.Marked(p) && !finalized.Has(p) {
+				// Swept anyway.
+				continue
+			}
+			for k := range h.Objects[p].Fields {
+				f := &h.Objects[p].Fields[k]
+				if !f.Weak || f.Pointer == Nil || s.Marked(f.Pointer) {
+					continue
+				}
+				f.Pointer, f.Interior = Nil, 0
+
+				// Yield cleared weak pointer state.
+				if !yield(s) {
+					return false
+				}
+			}
+		}
+	}
+	if !hasSpecials(roots) {
+		return true
+	}
+	ctx.Phase = specialPhase
+
+	// Yield new phase state.
+	if !yield(s) {
+		return false
+	}
+	for i := range roots {
+		r := &roots[i]
+		if r.Special == "" || r.Queued || !dead.Has(r.Object) {
+			continue
+		}
+		if r.Special == "cleanup" && finalized.Has(r.Object) {
+			// Cleanups wait until the object dies for good,
+			// after its finalizer has run.
+			continue
+		}
+		r.Queued = true
+		if r.Kind == FinalizerRoot {
+			mark(r.Object)
+		}
+		if r.Kind != SpecialRoot {
+			// Every special ends with the root for its function.
+			continue
+		}
+
+		// Yield queued special state.
+		if !yield(s) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasWeak reports whether any object in h has a weak pointer.
+func hasWeak(h *Heap) bool {
+	for _, obj := range h.Objects {
+		for _, f := range obj.Fields {
+			if f.Weak {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasSpecials reports whether any of roots are for specials that haven't
+// been queued yet.
+func hasSpecials(roots []Root) bool {
+	for _, r := range roots {
+		if r.Special != "" && !r.Queued {
+			return true
+		}
+	}
+	return false
+}
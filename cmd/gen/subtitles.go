@@ -0,0 +1,92 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// holdFor returns how long a frame with the given caption should be held,
+// which is the -hold duration or long enough to read the caption, whichever
+// is longer.
+func holdFor(caption string) time.Duration {
+	const perWord = 300 * time.Millisecond // About 200 words per minute.
+	return max(holdFlag, time.Duration(len(strings.Fields(caption)))*perWord)
+}
+
+// writeSRT writes the captions of r as a SubRip subtitle track, with one cue
+// per frame lasting for the frame's hold duration.
+func writeSRT(w io.Writer, r *Run) error {
+	return writeCues(w, r, "", ",", func(bw *bufio.Writer, i int) {
+		fmt.Fprintf(bw, "%d\n", i)
+	})
+}
+
+// writeVTT writes the captions of r as a WebVTT subtitle track, with one cue
+// per frame lasting for the frame's hold duration.
+func writeVTT(w io.Writer, r *Run) error {
+	return writeCues(w, r, "WEBVTT\n\n", ".", func(bw *bufio.Writer, i int) {
+		fmt.Fprintf(bw, "%s-%d\n", r.Name, i)
+	})
+}
+
+func writeCues(w io.Writer, r *Run, header, msSep string, id func(*bufio.Writer, int)) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(header)
+	var start time.Duration
+	n := 0
+	for i := 0; i < len(r.Frames); {
+		// Consecutive frames with the same caption, such as tween frames,
+		// share a cue.
+		caption := r.Frames[i].Caption
+		end := start
+		for ; i < len(r.Frames) && r.Frames[i].Caption == caption; i++ {
+			end += r.Frames[i].Hold
+		}
+		if caption != "" {
+			n++
+			id(bw, n)
+			fmt.Fprintf(bw, "%s --> %s\n%s\n\n", cueTime(start, msSep), cueTime(end, msSep), caption)
+		}
+		start = end
+	}
+	return bw.Flush()
+}
+
+// cueTime formats d as hh:mm:ss followed by msSep and milliseconds.
+func cueTime(d time.Duration, msSep string) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, msSep, ms%1000)
+}
+
+func saveSubtitles(base string, r *Run) error {
+	for _, t := range []struct {
+		ext   string
+		write func(io.Writer, *Run) error
+	}{
+		{"srt", writeSRT},
+		{"vtt", writeVTT},
+	} {
+		fname := base + "." + t.ext
+		fmt.Println("generating", fname)
+		f, err := os.Create(fname)
+		if err != nil {
+			return err
+		}
+		if err := t.write(f, r); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Theme controls the colors, line styles, and text sizes of frames.
+//
+// Theme files are JSON. Fields left out of a theme file keep their value
+// from the built-in theme named by Extends, which defaults to "light".
+type Theme struct {
+	Extends string `json:"extends,omitempty"`
+
+	Background Color `json:"background"`
+	Foreground Color `json:"foreground"` // Text, and visited objects and pointers.
+	Faded      Color `json:"faded"`      // Objects and pointers not visited yet.
+	Visited    Color `json:"visited"`    // Fill of visited objects.
+	Queued     Color `json:"queued"`     // Objects and blocks on the work list.
+	Active     Color `json:"active"`     // The root, block, object, or field being worked on.
+
+	Strokes ThemeStrokes `json:"strokes"`
+	Dashes  ThemeDashes  `json:"dashes"`
+	Fonts   ThemeFonts   `json:"fonts"`
+
+	// Patterns hatches queued and active objects and thickens the outline
+	// of the active block, so that state can be told apart without color,
+	// for example when printed in grayscale. -patterns turns it on for any
+	// theme.
+	Patterns bool `json:"patterns"`
+}
+
+// ThemeStrokes are line widths, in pixels at scale 1.
+type ThemeStrokes struct {
+	Panel    float64 `json:"panel"`    // Info and legend boxes.
+	Legend   float64 `json:"legend"`   // Legend swatches.
+	Block    float64 `json:"block"`    // Block outlines.
+	Object   float64 `json:"object"`   // Object outlines.
+	Field    float64 `json:"field"`    // Pointer fields and metadata bits.
+	Arrow    float64 `json:"arrow"`    // Pointers.
+	Divider  float64 `json:"divider"`  // Lines between side-by-side collectors.
+	WorkList float64 `json:"workList"` // Work list entries.
+}
+
+// ThemeDashes are dash patterns, in pixels at scale 1.
+type ThemeDashes struct {
+	Block        []float64 `json:"block"`        // Blocks that aren't queued or active.
+	Free         []float64 `json:"free"`         // Free slots.
+	Conservative []float64 `json:"conservative"` // Non-pointers that conservative scanning takes for pointers.
+	Weak         []float64 `json:"weak"`         // Weak pointers.
+}
+
+// ThemeFonts are font sizes, in points at scale 1.
+type ThemeFonts struct {
+	Info     float64 `json:"info"`
+	Legend   float64 `json:"legend"`
+	Root     float64 `json:"root"`
+	Block    float64 `json:"block"` // Block addresses.
+	Type     float64 `json:"type"`  // Object types.
+	Caption  float64 `json:"caption"`
+	Title    float64 `json:"title"` // Collector names when side by side.
+	WorkList float64 `json:"workList"`
+	Note     float64 `json:"note"`    // Small print, like "(next at top)".
+	Address  float64 `json:"address"` // Addresses, offsets, and pointer values.
+}
+
+// theme is the theme frames are drawn with, set by -theme.
+var theme, _ = builtinTheme("light")
+
+// builtinTheme returns the built-in theme with the given name.
+func builtinTheme(name string) (Theme, bool) {
+	t := Theme{
+		Background: Color{0xff, 0xff, 0xff, 0xff},
+		Foreground: Color{0x00, 0x00, 0x00, 0xff},
+		Faded:      Color{0x99, 0x99, 0x99, 0xff},
+		Visited:    Color{0xbb, 0xbb, 0xbb, 0xff},
+		Queued:     Color{0x00, 0x77, 0xbb, 0xff},
+		Active:     Color{0xcc, 0x33, 0x11, 0xff},
+		Strokes: ThemeStrokes{
+			Panel:    4,
+			Legend:   3,
+			Block:    2,
+			Object:   4,
+			Field:    2,
+			Arrow:    3,
+			Divider:  2,
+			WorkList: 3,
+		},
+		Dashes: ThemeDashes{
+			Block:        []float64{4},
+			Free:         []float64{2},
+			Conservative: []float64{12, 6},
+			Weak:         []float64{6, 6},
+		},
+		Fonts: ThemeFonts{
+			Info:     32,
+			Legend:   32,
+			Root:     36,
+			Block:    40,
+			Type:     28,
+			Caption:  32,
+			Title:    40,
+			WorkList: 28,
+			Note:     20,
+			Address:  14,
+		},
+	}
+	switch name {
+	case "light":
+	case "dark":
+		t.Background = Color{0x1e, 0x1e, 0x1e, 0xff}
+		t.Foreground = Color{0xe8, 0xe8, 0xe8, 0xff}
+		t.Faded = Color{0x80, 0x80, 0x80, 0xff}
+		t.Visited = Color{0x55, 0x55, 0x55, 0xff}
+		t.Queued = Color{0x33, 0xbb, 0xee, 0xff}
+		t.Active = Color{0xee, 0x77, 0x33, 0xff}
+	case "deuteranopia":
+		// Blue and orange from the Okabe-Ito palette, which stay
+		// distinct for red-green color blindness, backed up by patterns.
+		t.Queued = Color{0x00, 0x72, 0xb2, 0xff}
+		t.Active = Color{0xe6, 0x9f, 0x00, 0xff}
+		t.Patterns = true
+	default:
+		return Theme{}, false
+	}
+	return t, true
+}
+
+// loadTheme returns the built-in theme with the given name, or else reads
+// a theme file.
+func loadTheme(nameOrPath string) (Theme, error) {
+	if t, ok := builtinTheme(nameOrPath); ok {
+		return t, nil
+	}
+	b, err := os.ReadFile(nameOrPath)
+	if err != nil {
+		return Theme{}, err
+	}
+	var base struct {
+		Extends string `json:"extends"`
+	}
+	if err := json.Unmarshal(b, &base); err != nil {
+		return Theme{}, fmt.Errorf("%s: %v", nameOrPath, err)
+	}
+	if base.Extends == "" {
+		base.Extends = "light"
+	}
+	t, ok := builtinTheme(base.Extends)
+	if !ok {
+		return Theme{}, fmt.Errorf("%s: unknown theme %q to extend", nameOrPath, base.Extends)
+	}
+	if err := json.Unmarshal(b, &t); err != nil {
+		return Theme{}, fmt.Errorf("%s: %v", nameOrPath, err)
+	}
+	return t, nil
+}
+
+// Color is a color, encoded in JSON as "#rrggbb" or "#rrggbbaa".
+type Color color.RGBA
+
+func (c Color) RGBA() (r, g, b, a uint32) {
+	return color.RGBA(c).RGBA()
+}
+
+func (c Color) MarshalJSON() ([]byte, error) {
+	s := fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+	if c.A != 0xff {
+		s += fmt.Sprintf("%02x", c.A)
+	}
+	return json.Marshal(s)
+}
+
+func (c *Color) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 8 {
+		return fmt.Errorf("bad color %q: want #rrggbb or #rrggbbaa", s)
+	}
+	*c = Color{uint8(n >> 24), uint8(n >> 16), uint8(n >> 8), uint8(n)}
+	return nil
+}
+
+// lighten returns c mixed mostly with the background, for fills.
+func lighten(c Color) Color {
+	return mix(c, theme.Background, 0.8)
+}
+
+// lightenLess returns c mixed halfway with the background.
+func lightenLess(c Color) Color {
+	return mix(c, theme.Background, 0.5)
+}
+
+// mix returns c moved a fraction f of the way toward bg.
+func mix(c, bg Color, f float64) Color {
+	towards := func(x, y uint8) uint8 {
+		return uint8(float64(x) + (float64(y)-float64(x))*f)
+	}
+	return Color{towards(c.R, bg.R), towards(c.G, bg.G), towards(c.B, bg.B), c.A}
+}
+
+// drawHatch draws lines across the rectangle at x, y with size w, h in
+// the current color: diagonal ones, and if cross is set, ones in the
+// other direction too.
+func drawHatch(c canvas, x, y, w, h, spacing float64, cross bool) {
+	// Lines x+y = k (or x-y = k when flipped) clipped to the rectangle.
+	lines := func(flip bool) {
+		for k := spacing; k < w+h; k += spacing {
+			x0, y0 := math.Max(0, k-h), math.Min(k, h)
+			x1, y1 := math.Min(k, w), math.Max(0, k-w)
+			if flip {
+				x0, x1 = w-x0, w-x1
+			}
+			c.MoveTo(x+x0, y+y0)
+			c.LineTo(x+x1, y+y1)
+		}
+		c.Stroke()
+	}
+	lines(false)
+	if cross {
+		lines(true)
+	}
+}
+
+// patterns reports whether to draw patterns, because the theme or
+// -patterns calls for them.
+func patterns() bool {
+	return theme.Patterns || patternsFlag
+}
+
+// drawPattern hatches the box at x, y with size w, h to show that it's on
+// the work list, or cross-hatches it if it's active, when patterns are on.
+func drawPattern(c canvas, x, y, w, h, scale float64, active bool) {
+	if !patterns() {
+		return
+	}
+	col := theme.Queued
+	if active {
+		col = theme.Active
+	}
+	c.SetColor(col)
+	c.SetDash()
+	c.SetLineWidth(1.5 * scale)
+	drawHatch(c, x, y, w, h, 8*scale, active)
+}
+
+// scaleDashes returns the dash pattern d multiplied by scale.
+func scaleDashes(d []float64, scale float64) []float64 {
+	scaled := make([]float64, len(d))
+	for i, v := range d {
+		scaled[i] = v * scale
+	}
+	return scaled
+}
@@ -0,0 +1,69 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "fmt"
+
+// maxTinySize is the size of the blocks the tiny allocator packs small,
+// pointer-free allocations into.
+const maxTinySize = 16
+
+// tinyType is the type of objects holding tiny allocations.
+const tinyType = "tiny"
+
+// TinyAlloc is one of the allocations the tiny allocator packed into an
+// object. The collector knows nothing of them: as far as it's concerned,
+// there's just the object, so a pointer to any one of them keeps all the
+// others alive too.
+type TinyAlloc struct {
+	Type   string
+	Offset int
+	Size   int
+}
+
+// packTiny adds an allocation of size bytes after the ones already in o,
+// aligned the way the runtime's tiny allocator aligns it.
+func (o *Object) packTiny(typ string, size int) error {
+	if size <= 0 || size >= maxTinySize {
+		return fmt.Errorf("tiny allocation %s: size %d is not between 1 and %d", typ, size, maxTinySize-1)
+	}
+	off := 0
+	if n := len(o.Tiny); n != 0 {
+		off = o.Tiny[n-1].Offset + o.Tiny[n-1].Size
+	}
+	switch {
+	case size&7 == 0:
+		off = (off + 7) &^ 7
+	case size&3 == 0:
+		off = (off + 3) &^ 3
+	case size&1 == 0:
+		off = (off + 1) &^ 1
+	}
+	if off+size > maxTinySize {
+		return fmt.Errorf("tiny allocation %s: %d bytes at offset %d don't fit in a %d-byte block", typ, size, off, maxTinySize)
+	}
+	o.Tiny = append(o.Tiny, TinyAlloc{typ, off, size})
+	return nil
+}
+
+// drawTiny draws the tiny allocations in obj, which is drawn at x, y with
+// words w wide and h high, as boxes labeled with their types.
+func drawTiny(c canvas, x, y, w, h, scale float64, obj *Object, marked bool) {
+	if marked {
+		c.SetColor(theme.Foreground)
+	} else {
+		c.SetColor(theme.Faded)
+	}
+	c.SetDash()
+	c.SetLineWidth(scale * theme.Strokes.Field)
+	must(setFontFace(c, monoFont, scale*theme.Fonts.Address))
+	byteWidth := w / PointerSize
+	for _, a := range obj.Tiny {
+		ax, aw := x+float64(a.Offset)*byteWidth, float64(a.Size)*byteWidth
+		c.DrawRectangle(ax, y, aw, h)
+		c.Stroke()
+		c.DrawStringAnchored(fitString(c, a.Type, aw-scale*4), ax+aw/2, y+h/2, 0.5, 0.35)
+	}
+}
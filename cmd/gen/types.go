@@ -0,0 +1,341 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// TypeDecl is a Go type declaration for objects in the heap, like
+// {"T", "struct{children *[4]*T; value int}"}.
+type TypeDecl struct {
+	Name string
+	Def  string
+}
+
+// typeLayout is what the collector knows about a type: its size, and
+// which of its words hold pointers.
+type typeLayout struct {
+	size  int // In bytes, rounded up to a whole number of words.
+	words []typeWord
+}
+
+type typeWord struct {
+	name    string // Name of the field that starts the word, if any.
+	pointer bool
+}
+
+// typeEnv works out the layouts of type expressions that may refer to a
+// set of declared types.
+type typeEnv struct {
+	decls     map[string]ast.Expr
+	resolving map[string]bool
+}
+
+func newTypeEnv(decls []TypeDecl) (*typeEnv, error) {
+	e := &typeEnv{decls: make(map[string]ast.Expr), resolving: make(map[string]bool)}
+	for _, d := range decls {
+		if !token.IsIdentifier(d.Name) {
+			return nil, fmt.Errorf("type %q: name is not an identifier", d.Name)
+		}
+		if _, ok := e.decls[d.Name]; ok {
+			return nil, fmt.Errorf("type %s declared more than once", d.Name)
+		}
+		x, err := parser.ParseExpr(d.Def)
+		if err != nil {
+			return nil, fmt.Errorf("type %s: %v", d.Name, err)
+		}
+		e.decls[d.Name] = x
+	}
+	for _, d := range decls {
+		if _, err := e.layout(d.Name); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// layout returns the layout of the type expression typ.
+func (e *typeEnv) layout(typ string) (*typeLayout, error) {
+	x, err := parser.ParseExpr(typ)
+	if err != nil {
+		return nil, fmt.Errorf("type %s: %v", typ, err)
+	}
+	var ptrs []int
+	names := make(map[int]string)
+	size, _, err := e.walk(x, 0, "", func(off int, name string, ptr bool) {
+		if ptr {
+			ptrs = append(ptrs, off)
+		}
+		if _, ok := names[off/PointerSize]; !ok && name != "" {
+			names[off/PointerSize] = name
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("type %s: %v", typ, err)
+	}
+	l := &typeLayout{size: (size + PointerSize - 1) / PointerSize * PointerSize}
+	l.words = make([]typeWord, l.size/PointerSize)
+	for i := range l.words {
+		l.words[i].name = names[i]
+	}
+	for _, off := range ptrs {
+		l.words[off/PointerSize].pointer = true
+	}
+	return l, nil
+}
+
+// Sizes and alignments of basic types.
+var basicSizes = map[string]int{
+	"bool": 1, "int8": 1, "uint8": 1, "byte": 1,
+	"int16": 2, "uint16": 2,
+	"int32": 4, "uint32": 4, "rune": 4, "float32": 4,
+	"int": 8, "uint": 8, "int64": 8, "uint64": 8, "uintptr": 8, "float64": 8,
+}
+
+// walk calls visit for each part of type x placed at offset off, naming
+// them after name, and returns the size and alignment of x.
+func (e *typeEnv) walk(x ast.Expr, off int, name string, visit func(off int, name string, ptr bool)) (size, align int, err error) {
+	switch x := x.(type) {
+	case *ast.ParenExpr:
+		return e.walk(x.X, off, name, visit)
+
+	case *ast.Ident:
+		if n, ok := basicSizes[x.Name]; ok {
+			visit(off, name, false)
+			return n, n, nil
+		}
+		switch x.Name {
+		case "complex64":
+			visit(off, name, false)
+			return 8, 4, nil
+		case "complex128":
+			visit(off, name, false)
+			visit(off+8, "", false)
+			return 16, 8, nil
+		case "string":
+			visit(off, name, true)
+			visit(off+8, lenName(name), false)
+			return 16, 8, nil
+		case "any", "error":
+			visit(off, name, true)
+			visit(off+8, dataName(name), true)
+			return 16, 8, nil
+		}
+		def, ok := e.decls[x.Name]
+		if !ok {
+			return 0, 0, fmt.Errorf("unknown type %s", x.Name)
+		}
+		if e.resolving[x.Name] {
+			return 0, 0, fmt.Errorf("invalid recursive type %s", x.Name)
+		}
+		e.resolving[x.Name] = true
+		defer delete(e.resolving, x.Name)
+		return e.walk(def, off, name, visit)
+
+	case *ast.SelectorExpr:
+		if pkg, ok := x.X.(*ast.Ident); ok && pkg.Name == "unsafe" && x.Sel.Name == "Pointer" {
+			visit(off, name, true)
+			return PointerSize, PointerSize, nil
+		}
+		return 0, 0, fmt.Errorf("unknown type %s", exprString(x))
+
+	case *ast.StarExpr, *ast.MapType, *ast.ChanType, *ast.FuncType:
+		visit(off, name, true)
+		return PointerSize, PointerSize, nil
+
+	case *ast.InterfaceType:
+		visit(off, name, true)
+		visit(off+8, dataName(name), true)
+		return 16, 8, nil
+
+	case *ast.ArrayType:
+		if x.Len == nil {
+			visit(off, name, true)
+			visit(off+8, lenName(name), false)
+			visit(off+16, "cap("+name+")", false)
+			return 24, 8, nil
+		}
+		lit, ok := x.Len.(*ast.BasicLit)
+		if !ok || lit.Kind != token.INT {
+			return 0, 0, fmt.Errorf("array length %s is not an integer literal", exprString(x.Len))
+		}
+		n, err := strconv.Atoi(lit.Value)
+		if err != nil {
+			return 0, 0, fmt.Errorf("bad array length %s", lit.Value)
+		}
+		// Measure the element type without visiting it, then lay out
+		// each element.
+		esize, ealign, err := e.walk(x.Elt, 0, "", func(int, string, bool) {})
+		if err != nil {
+			return 0, 0, err
+		}
+		for i := range n {
+			if _, _, err := e.walk(x.Elt, off+i*esize, fmt.Sprintf("%s[%d]", name, i), visit); err != nil {
+				return 0, 0, err
+			}
+		}
+		return n * esize, max(ealign, 1), nil
+
+	case *ast.StructType:
+		size, align = 0, 1
+		for _, f := range x.Fields.List {
+			names := make([]string, 0, len(f.Names))
+			for _, id := range f.Names {
+				names = append(names, id.Name)
+			}
+			if len(names) == 0 {
+				// Embedded field.
+				names = append(names, strings.TrimPrefix(exprString(f.Type), "*"))
+			}
+			for _, n := range names {
+				if name != "" {
+					n = name + "." + n
+				}
+				fsize, falign, err := e.walk(f.Type, 0, "", func(int, string, bool) {})
+				if err != nil {
+					return 0, 0, err
+				}
+				if falign > 0 {
+					size = (size + falign - 1) / falign * falign
+				}
+				if _, _, err := e.walk(f.Type, off+size, n, visit); err != nil {
+					return 0, 0, err
+				}
+				size += fsize
+				align = max(align, falign)
+			}
+		}
+		return (size + align - 1) / align * align, align, nil
+	}
+	return 0, 0, fmt.Errorf("unsupported type %s", exprString(x))
+}
+
+func lenName(name string) string {
+	if name == "" {
+		return ""
+	}
+	return "len(" + name + ")"
+}
+
+func dataName(name string) string {
+	if name == "" {
+		return ""
+	}
+	return name + ".data"
+}
+
+// exprString formats the type expression x.
+func exprString(x ast.Expr) string {
+	var buf bytes.Buffer
+	format.Node(&buf, token.NewFileSet(), x)
+	return buf.String()
+}
+
+// applyTypes checks the objects in h against their types as declared in
+// h.Types, fills in the pointer fields and scalar words they leave out,
+// and names them. If h declares no types, objects are left as they are.
+func (h *Heap) applyTypes() error {
+	if len(h.Types) == 0 {
+		return nil
+	}
+	env, err := newTypeEnv(h.Types)
+	if err != nil {
+		return err
+	}
+	for i := range h.Blocks {
+		b := &h.Blocks[i]
+		for _, p := range b.Objects {
+			if p == Free {
+				continue
+			}
+			obj := &h.Objects[p]
+			if len(obj.Tiny) != 0 {
+				// Tiny allocations have no pointers to check.
+				continue
+			}
+			l, err := env.layout(obj.Type)
+			if err != nil {
+				return fmt.Errorf("object at %#x: %v", h.AddressOf(p), err)
+			}
+			if err := obj.applyLayout(l, b); err != nil {
+				return fmt.Errorf("object %s at %#x: %v", obj.Type, h.AddressOf(p), err)
+			}
+		}
+	}
+	return nil
+}
+
+// applyLayout checks o against layout l and fills in its words from it.
+func (o *Object) applyLayout(l *typeLayout, b *Block) error {
+	if l.size > b.ElemSize {
+		return fmt.Errorf("%d bytes don't fit in a %d-byte slot", l.size, b.ElemSize)
+	}
+	if o.Size != 0 && o.Size != l.size {
+		return fmt.Errorf("size %d doesn't match the type's size %d", o.Size, l.size)
+	}
+	if l.size < b.ElemSize {
+		o.Size = l.size
+	}
+
+	has := make(map[int]bool)
+	for k := range o.Fields {
+		f := &o.Fields[k]
+		w := f.Offset / PointerSize
+		if w >= len(l.words) || !l.words[w].pointer {
+			return fmt.Errorf("offset %d doesn't hold a pointer", f.Offset)
+		}
+		f.Name = l.words[w].name
+		has[w] = true
+	}
+	for k := range o.Scalars {
+		sc := &o.Scalars[k]
+		w := sc.Offset / PointerSize
+		if w >= len(l.words) || l.words[w].pointer {
+			return fmt.Errorf("offset %d holds a pointer, not a scalar", sc.Offset)
+		}
+		sc.Name = l.words[w].name
+		has[w] = true
+	}
+	for w, tw := range l.words {
+		if has[w] {
+			continue
+		}
+		if tw.pointer {
+			o.Fields = append(o.Fields, Field{Offset: w * PointerSize, Pointer: Nil, Name: tw.name})
+		} else {
+			o.Scalars = append(o.Scalars, Scalar{Offset: w * PointerSize, Name: tw.name})
+		}
+	}
+	// Collectors visit fields in order.
+	slices.SortFunc(o.Fields, func(a, b Field) int { return cmp.Compare(a.Offset, b.Offset) })
+	slices.SortFunc(o.Scalars, func(a, b Scalar) int { return cmp.Compare(a.Offset, b.Offset) })
+	return nil
+}
+
+// formatTypes returns decls formatted as Go type declarations.
+func formatTypes(decls []TypeDecl) string {
+	var src strings.Builder
+	src.WriteString("package p\n")
+	for _, d := range decls {
+		fmt.Fprintf(&src, "type %s %s\n", d.Name, d.Def)
+	}
+	// The declarations were checked when the heap was made.
+	out, err := format.Source([]byte(src.String()))
+	must(err)
+	s := strings.TrimPrefix(string(out), "package p\n")
+	s = strings.TrimSpace(s)
+	// Keep indentation from being trimmed when the text is wrapped.
+	return strings.ReplaceAll(s, "\t", "\u2800   ")
+}
@@ -0,0 +1,113 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// layoutString formats l as its size followed by its words, with pointer
+// words starred and unnamed ones shown as "_", like "24: *left *right val".
+func layoutString(l *typeLayout) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d:", l.size)
+	for _, w := range l.words {
+		b.WriteString(" ")
+		if w.pointer {
+			b.WriteString("*")
+		}
+		if w.name == "" {
+			w.name = "_"
+		}
+		b.WriteString(w.name)
+	}
+	return b.String()
+}
+
+func TestLayout(t *testing.T) {
+	env, err := newTypeEnv([]TypeDecl{
+		{"T", "struct{left *T; right *T; val int}"},
+		{"Pair", "struct{a, b *T}"},
+		{"Wrap", "struct{Pair; n int32}"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		typ  string
+		want string
+	}{
+		{"int", "8: _"},
+		{"*int", "8: *_"},
+		{"bool", "8: _"},
+		{"string", "16: *_ _"},
+		{"any", "16: *_ *_"},
+		{"map[int]int", "8: *_"},
+		{"T", "24: *left *right val"},
+		{"struct{a bool; b int32; c *int}", "16: a *c"},
+		{"struct{a int8; b int16; c int32}", "8: a"},
+		{"struct{s []byte; e error}", "40: *s len(s) cap(s) *e *e.data"},
+		{"struct{name string}", "16: *name len(name)"},
+		{"[3]*T", "24: *[0] *[1] *[2]"},
+		{"[2]T", "48: *[0].left *[0].right [0].val *[1].left *[1].right [1].val"},
+		{"[0]*T", "0:"},
+		{"Pair", "16: *a *b"},
+		{"Wrap", "24: *Pair.a *Pair.b n"},
+		{"struct{p unsafe.Pointer; c chan int; f func()}", "24: *p *c *f"},
+	}
+	for _, tt := range tests {
+		l, err := env.layout(tt.typ)
+		if err != nil {
+			t.Errorf("layout(%q): %v", tt.typ, err)
+			continue
+		}
+		if got := layoutString(l); got != tt.want {
+			t.Errorf("layout(%q) = %q, want %q", tt.typ, got, tt.want)
+		}
+	}
+}
+
+func TestLayoutErrors(t *testing.T) {
+	env, err := newTypeEnv([]TypeDecl{{"T", "struct{next *T}"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		typ  string
+		want string
+	}{
+		{"U", "unknown type U"},
+		{"struct{x os.File}", "unknown type os.File"},
+		{"[n]int", "not an integer literal"},
+		{"struct{", "type struct{"},
+	}
+	for _, tt := range tests {
+		_, err := env.layout(tt.typ)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("layout(%q) error = %v, want one containing %q", tt.typ, err, tt.want)
+		}
+	}
+}
+
+func TestNewTypeEnvErrors(t *testing.T) {
+	tests := []struct {
+		decls []TypeDecl
+		want  string
+	}{
+		{[]TypeDecl{{"R", "struct{r R}"}}, "invalid recursive type R"},
+		{[]TypeDecl{{"A", "struct{b B}"}, {"B", "[2]A"}}, "invalid recursive type"},
+		{[]TypeDecl{{"T", "int"}, {"T", "int"}}, "declared more than once"},
+		{[]TypeDecl{{"1T", "int"}}, "not an identifier"},
+		{[]TypeDecl{{"T", "Missing"}}, "unknown type Missing"},
+	}
+	for _, tt := range tests {
+		_, err := newTypeEnv(tt.decls)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("newTypeEnv(%v) error = %v, want one containing %q", tt.decls, err, tt.want)
+		}
+	}
+}
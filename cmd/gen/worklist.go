@@ -0,0 +1,215 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"image"
+)
+
+// WorkItem is an entry on a collector's work list: either an object or,
+// if Block is non-nil, a whole block. If Offset is non-zero, the entry is
+// only the oblet of Object that starts there.
+type WorkItem struct {
+	Object Pointer
+	Block  *Block
+	Offset int
+}
+
+// WorkList is one of a collector's work lists, with its items in the order
+// they'll be taken off of it.
+type WorkList struct {
+	Name  string // Like "work queue".
+	Items []WorkItem
+	LIFO  bool // Whether it's a stack rather than a queue.
+}
+
+// workItemKey identifies a WorkItem across different copies of the heap.
+type workItemKey struct {
+	object Pointer
+	block  int
+	offset int
+}
+
+func (w WorkItem) key(h *Heap) workItemKey {
+	if w.Block != nil {
+		return workItemKey{Nil, blockIndex(h, w.Block), 0}
+	}
+	return workItemKey{w.Object, -1, w.Offset}
+}
+
+func (w WorkItem) label(h *Heap) string {
+	if w.Block != nil {
+		return fmt.Sprintf("block %s", w.Block.Name())
+	}
+	if w.Offset != 0 {
+		return fmt.Sprintf("oblet %d of %s", w.Offset/obletSize, h.Objects[w.Object].Type)
+	}
+	return fmt.Sprintf("%s %#x", h.Objects[w.Object].Type, h.AddressOf(w.Object))
+}
+
+// tween describes a frame partway between two steps, for animating the
+// transition from one to the next.
+type tween struct {
+	from gcState
+	t    float64 // In (0, 1).
+}
+
+// workListChanged reports whether the work lists of a and b differ.
+func workListChanged(a, b gcState) bool {
+	wa, aok := a.(gcStateWorkList)
+	wb, bok := b.(gcStateWorkList)
+	if !aok || !bok {
+		return false
+	}
+	la, lb := wa.WorkLists(), wb.WorkLists()
+	if len(la) != len(lb) {
+		return true
+	}
+	for i := range la {
+		ia, ib := la[i].Items, lb[i].Items
+		if len(ia) != len(ib) {
+			return true
+		}
+		for j := range ia {
+			if ia[j].key(a.Heap()) != ib[j].key(b.Heap()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// drawWorkList draws s's work lists in area, one above the other, each as
+// a vertical strip of entries in the order they'll be taken off the list.
+// If tw is non-nil, entries are drawn partway between their positions in
+// tw.from and s: pushed entries slide in from the right, and popped
+// entries slide out to the left. All sizes are multiplied by scale.
+func drawWorkList(c canvas, area image.Rectangle, s gcState, tw *tween, scale float64) {
+	wl, ok := s.(gcStateWorkList)
+	if !ok {
+		return
+	}
+	lists := wl.WorkLists()
+	var fromLists []WorkList
+	if tw != nil {
+		fromLists = tw.from.(gcStateWorkList).WorkLists()
+	}
+	for i, list := range lists {
+		sub := area
+		sub.Min.Y = area.Min.Y + area.Dy()*i/len(lists)
+		sub.Max.Y = area.Min.Y + area.Dy()*(i+1)/len(lists)
+		var from *WorkList
+		if tw != nil && i < len(fromLists) {
+			from = &fromLists[i]
+		}
+		drawWorkListEntries(c, sub, s.Heap(), list, tw, from, scale)
+	}
+}
+
+// drawWorkListEntries draws one work list of a state with heap h into
+// area. If tw is non-nil, from is the same list in tw.from.
+func drawWorkListEntries(c canvas, area image.Rectangle, h *Heap, list WorkList, tw *tween, from *WorkList, scale float64) {
+	items := list.Items
+
+	padding := 16 * scale
+	entryHeight := 48 * scale
+	entryGap := 8 * scale
+	x := float64(area.Min.X) + padding
+	width := float64(area.Dx()) - 2*padding
+
+	c.SetColor(theme.Foreground)
+	must(setFontFace(c, monoFont, theme.Fonts.WorkList*scale))
+	if w, _ := c.MeasureString(list.Name); w > width {
+		must(setFontFace(c, monoFont, theme.Fonts.WorkList*scale*width/w))
+	}
+	c.DrawStringAnchored(list.Name, x+width/2, float64(area.Min.Y)+padding, 0.5, 0.5)
+	must(setFontFace(c, italicFont, theme.Fonts.Note*scale))
+	c.DrawStringAnchored("(next at top)", x+width/2, float64(area.Min.Y)+padding+32*scale, 0.5, 0.5)
+	must(setFontFace(c, monoFont, theme.Fonts.Note*scale))
+
+	top := float64(area.Min.Y) + padding + 64*scale
+	maxEntries := int((float64(area.Max.Y) - top) / (entryHeight + entryGap))
+	posY := func(i int) float64 {
+		return top + float64(i)*(entryHeight+entryGap)
+	}
+
+	type entry struct {
+		label string
+		y     float64
+		dx    float64
+		alpha float64
+	}
+	var entries []entry
+	if tw == nil {
+		for i, it := range items {
+			entries = append(entries, entry{it.label(h), posY(i), 0, 1})
+		}
+	} else {
+		var fromItems []WorkItem
+		if from != nil {
+			fromItems = from.Items
+		}
+		fh := tw.from.Heap()
+		fromIdx := make(map[workItemKey]int)
+		for i, it := range fromItems {
+			fromIdx[it.key(fh)] = i
+		}
+		toKeys := make(map[workItemKey]bool)
+		for i, it := range items {
+			k := it.key(h)
+			toKeys[k] = true
+			if j, ok := fromIdx[k]; ok {
+				entries = append(entries, entry{it.label(h), lerp(posY(j), posY(i), tw.t), 0, 1})
+			} else {
+				entries = append(entries, entry{it.label(h), posY(i), (1 - tw.t) * width, tw.t})
+			}
+		}
+		for j, it := range fromItems {
+			if !toKeys[it.key(fh)] {
+				entries = append(entries, entry{it.label(fh), posY(j), -tw.t * width, 1 - tw.t})
+			}
+		}
+	}
+
+	c.SetLineWidth(theme.Strokes.WorkList * scale)
+	c.SetDash()
+	for i, e := range entries {
+		if i >= maxEntries && tw == nil {
+			c.SetColor(theme.Queued)
+			c.DrawStringAnchored(fmt.Sprintf("+%d more", len(entries)-i), x+width/2, e.y+entryHeight/2, 0.5, 0.5)
+			break
+		}
+		if e.y+entryHeight > float64(area.Max.Y) {
+			continue
+		}
+		ex := x + e.dx
+		c.SetColor(fade(lighten(theme.Queued), e.alpha))
+		c.DrawRectangle(ex, e.y, width, entryHeight)
+		c.Fill()
+		c.SetColor(fade(theme.Queued, e.alpha))
+		c.DrawRectangle(ex, e.y, width, entryHeight)
+		c.Stroke()
+		c.DrawStringAnchored(fitString(c, e.label, width-8*scale), ex+width/2, e.y+entryHeight/2, 0.5, 0.35)
+	}
+	if len(entries) == 0 {
+		c.SetColor(theme.Faded)
+		c.DrawStringAnchored("(empty)", x+width/2, top+entryHeight/2, 0.5, 0.35)
+	}
+}
+
+func lerp(a, b, t float64) float64 {
+	return a + (b-a)*t
+}
+
+// fade returns c with its alpha scaled by alpha.
+func fade(c Color, alpha float64) Color {
+	return Color{
+		R: uint8(float64(c.R) * alpha),
+		G: uint8(float64(c.G) * alpha),
+		B: uint8(float64(c.B) * alpha),
+		A: uint8(float64(c.A) * alpha),
+	}
+}
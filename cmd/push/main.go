@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"time"
 
 	"google.golang.org/api/drive/v2"
@@ -29,6 +30,11 @@ func main() {
 		log.Fatal(err)
 	}
 	for _, e := range entries {
+		// Slides only accepts raster images, and gen may also write
+		// subtitle tracks and HTML bundles here.
+		if filepath.Ext(e.Name()) != ".png" {
+			continue
+		}
 		imageURL := "https://raw.githubusercontent.com/mknyszek/greentea-visuals/refs/heads/main/img/" + e.Name()
 		log.Print("pushing ", imageURL)
 		if err := createImageSlide(*presentationID, imageURL); err != nil {